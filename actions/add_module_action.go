@@ -12,6 +12,7 @@ type AddModuleAction struct {
 	Fields       []string `json:"fields"`
 	Permission   []string `json:"permission"`
 	Auth         bool     `json:"auth"`
+	Strict       bool     `json:"strict"`
 }
 
 func (action AddModuleAction) Action() ModuleActionName {
@@ -0,0 +1,107 @@
+package actions
+
+import (
+	"github.com/gin-gonic/gin"
+)
+
+type MetricFunction string
+
+const (
+	MetricFunctionSum   MetricFunction = "sum"
+	MetricFunctionAvg   MetricFunction = "avg"
+	MetricFunctionMin   MetricFunction = "min"
+	MetricFunctionMax   MetricFunction = "max"
+	MetricFunctionCount MetricFunction = "count"
+)
+
+type DateTruncInterval string
+
+const (
+	DateTruncIntervalHour  DateTruncInterval = "hour"
+	DateTruncIntervalDay   DateTruncInterval = "day"
+	DateTruncIntervalMonth DateTruncInterval = "month"
+)
+
+type DateFieldFormat string
+
+const (
+	DateFieldFormatTimestamp DateFieldFormat = "timestamp"
+	DateFieldFormatUnix      DateFieldFormat = "unix"
+	DateFieldFormatUnixMilli DateFieldFormat = "unix_milli"
+)
+
+// ModuleActionMetric is an aggregate over a module field,
+// count metrics may leave Field empty to count rows.
+type ModuleActionMetric struct {
+	Name     string         `json:"name"`
+	Function MetricFunction `json:"function"`
+	Field    string         `json:"field"`
+}
+
+// ModuleActionDateTrunc groups rows by a date field truncated
+// to one of the allowed intervals.
+type ModuleActionDateTrunc struct {
+	Field     string              `json:"field"`
+	Format    DateFieldFormat     `json:"format"`
+	Intervals []DateTruncInterval `json:"intervals"`
+}
+
+// AllowInterval reports whether the interval may be requested,
+// every interval is allowed when Intervals is empty.
+func (dateTrunc ModuleActionDateTrunc) AllowInterval(interval DateTruncInterval) bool {
+	switch interval {
+	case DateTruncIntervalHour, DateTruncIntervalDay, DateTruncIntervalMonth:
+	default:
+		return false
+	}
+
+	if len(dateTrunc.Intervals) == 0 {
+		return true
+	}
+	for _, allowed := range dateTrunc.Intervals {
+		if allowed == interval {
+			return true
+		}
+	}
+	return false
+}
+
+// AggregateModuleAction groups the module rows by whitelisted fields and
+// returns metrics per group plus totals. Joins only filter and search,
+// every matching parent row counts once in the metrics.
+type AggregateModuleAction struct {
+	ModuleAction
+	BeforeAction func(c *gin.Context) error
+	AfterAction  func(c *gin.Context)
+	Label        string                                  `json:"label"`
+	GroupBy      []string                                `json:"group_by"`
+	DateTrunc    *ModuleActionDateTrunc                  `json:"date_trunc,omitempty"`
+	Metrics      []ModuleActionMetric                    `json:"metrics"`
+	Permission   []string                                `json:"permission"`
+	Auth         bool                                    `json:"auth"`
+	Join         []ModuleActionJoin                      `json:"join"`
+	Where        func(c *gin.Context) *ModuleActionWhere `json:"where"`
+	Extra        interface{}                             `json:"extra"`
+	Search       []ModuleActionSearchField               `json:"search"`
+	FullText     *ModuleActionFullTextSearch             `json:"full_text,omitempty"`
+	Filter       []string                                `json:"filter"`
+}
+
+func (action AggregateModuleAction) Action() ModuleActionName {
+	return ModuleActionNameAggregate
+}
+
+func (action AggregateModuleAction) BeforeRequest(c *gin.Context) error {
+	if action.BeforeAction == nil {
+		return nil
+	}
+
+	return action.BeforeAction(c)
+}
+func (action AggregateModuleAction) AfterRequest(c *gin.Context) {
+	if action.AfterAction == nil {
+		return
+	}
+
+	action.AfterAction(c)
+}
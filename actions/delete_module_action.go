@@ -10,6 +10,8 @@ type DeleteModuleAction struct {
 	Permission   []string      `json:"permission"`
 	Auth         bool          `json:"auth"`
 	By           []interface{} `json:"by"`
+	// Where narrows the records which may be deleted, a record outside of it is not found.
+	Where func(c *gin.Context) *ModuleActionWhere `json:"where"`
 }
 
 func (action DeleteModuleAction) Action() ModuleActionName {
@@ -17,8 +17,13 @@ type ListModuleAction struct {
 	Join         []ModuleActionJoin                      `json:"join"`
 	Where        func(c *gin.Context) *ModuleActionWhere `json:"where"`
 	Extra        interface{}                             `json:"extra"`
-	Search       []string                                `json:"search"`
+	Search       []ModuleActionSearchField               `json:"search"`
+	FullText     *ModuleActionFullTextSearch             `json:"full_text,omitempty"`
 	Filter       []string                                `json:"filter"`
+	// Sort lists the fields which may be requested by the sort query param.
+	Sort []string `json:"sort"`
+	// Relations are optional joins run only when named by the include query param.
+	Relations []ModuleActionJoin `json:"relations"`
 }
 
 func (action ListModuleAction) Action() ModuleActionName {
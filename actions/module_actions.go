@@ -1,18 +1,21 @@
 package actions
 
 import (
+	"strings"
+
 	"github.com/gin-gonic/gin"
 )
 
 type ModuleActionName string
 
 const (
-	ModuleActionNameList   ModuleActionName = "list"
-	ModuleActionNameAdd    ModuleActionName = "add"
-	ModuleActionNameDefrec ModuleActionName = "defrec"
-	ModuleActionNameView   ModuleActionName = "view"
-	ModuleActionNameUpdate ModuleActionName = "update"
-	ModuleActionNameDelete ModuleActionName = "delete"
+	ModuleActionNameList      ModuleActionName = "list"
+	ModuleActionNameAdd       ModuleActionName = "add"
+	ModuleActionNameDefrec    ModuleActionName = "defrec"
+	ModuleActionNameView      ModuleActionName = "view"
+	ModuleActionNameUpdate    ModuleActionName = "update"
+	ModuleActionNameDelete    ModuleActionName = "delete"
+	ModuleActionNameAggregate ModuleActionName = "aggregate"
 )
 
 type ModuleAction interface {
@@ -44,6 +47,13 @@ func NewJoin(tableName string, joinType JoinType, onParentKey string, onKey stri
 	}
 }
 
+// NewNestedJoin joins tableName on onParentKey of the join aliased parent.
+func NewNestedJoin(parent string, tableName string, joinType JoinType, onParentKey string, onKey string, fields []string, resultArrayName string) ModuleActionJoin {
+	join := NewJoin(tableName, joinType, onParentKey, onKey, fields, resultArrayName)
+	join.Parent = parent
+	return join
+}
+
 type ModuleActionWhereConditionType string
 
 const (
@@ -51,9 +61,34 @@ const (
 	ModuleActionWhereConditionTypeOR  ModuleActionWhereConditionType = "OR"
 )
 
+// ModuleActionWhere matches rows by all of its parts: the legacy Fields
+// joined by their condition types, the Condition tree and every clause of And.
 type ModuleActionWhere struct {
-	Fields []ModuleActionWhereField `json:"fields"`
-	Values []interface{}            `json:"values"`
+	Fields    []ModuleActionWhereField `json:"fields"`
+	Values    []interface{}            `json:"values"`
+	Condition *ModuleActionCondition   `json:"condition,omitempty"`
+	And       []*ModuleActionWhere     `json:"and,omitempty"`
+}
+
+// AndWhere combines the where clauses so that all of them must match,
+// nil clauses are skipped.
+func AndWhere(wheres ...*ModuleActionWhere) *ModuleActionWhere {
+	and := make([]*ModuleActionWhere, 0, len(wheres))
+	for _, where := range wheres {
+		if where != nil {
+			and = append(and, where)
+		}
+	}
+
+	if len(and) == 0 {
+		return nil
+	}
+	if len(and) == 1 {
+		return and[0]
+	}
+	return &ModuleActionWhere{
+		And: and,
+	}
 }
 
 type ModuleActionWhereField struct {
@@ -71,11 +106,114 @@ const (
 	JoinTypeInner      JoinType = "INNER"
 )
 
+// JoinShape is the output of a join per row: an array of all
+// matching rows or a single object which is null without a match.
+type JoinShape string
+
+const (
+	JoinShapeArray  JoinShape = "array"
+	JoinShapeObject JoinShape = "object"
+)
+
+// ModuleActionJoin joins TableName as ResultArrayName on OnParentKey of the module
+// table, or of the join aliased Parent which must be declared before it. Nested
+// joins are output inside every row of their parent join.
 type ModuleActionJoin struct {
-	TableName       string   `json:"table_name"`
-	Type            JoinType `json:"type"`
-	OnParentKey     string   `json:"on"`
-	OnKey           string   `json:"on_key"`
-	Fields          []string `json:"fields"`
-	ResultArrayName string   `json:"result_array_name"`
+	TableName       string    `json:"table_name"`
+	Type            JoinType  `json:"type"`
+	OnParentKey     string    `json:"on"`
+	OnKey           string    `json:"on_key"`
+	Fields          []string  `json:"fields"`
+	ResultArrayName string    `json:"result_array_name"`
+	Parent          string    `json:"parent,omitempty"`
+	Shape           JoinShape `json:"shape,omitempty"`
+	// Batch loads the join by one separate query for all rows instead of
+	// aggregating it in the main query, batched joins can not be nested.
+	Batch bool `json:"batch,omitempty"`
+}
+
+// IsNested reports whether the join is joined on another join.
+func (join ModuleActionJoin) IsNested() bool {
+	return len(join.Parent) > 0 && join.Parent != "parent"
+}
+
+// ModuleActionFilter is a parsed list filter: one value matches by equality and
+// several match any of them, Min and Max are inclusive bounds, nil bounds are open.
+type ModuleActionFilter struct {
+	Values []interface{} `json:"values,omitempty"`
+	Min    interface{}   `json:"min,omitempty"`
+	Max    interface{}   `json:"max,omitempty"`
+}
+
+// NewFilter matches any of values.
+func NewFilter(values ...interface{}) ModuleActionFilter {
+	return ModuleActionFilter{
+		Values: values,
+	}
+}
+
+// ModuleActionSort orders the list by a module field.
+type ModuleActionSort struct {
+	Name string `json:"name"`
+	Desc bool   `json:"desc"`
+}
+
+type SearchMode string
+
+const (
+	SearchModeContains SearchMode = "contains"
+	SearchModePrefix   SearchMode = "prefix"
+	SearchModeExact    SearchMode = "exact"
+	SearchModeTrigram  SearchMode = "trigram"
+)
+
+// ModuleActionSearchField is a column matched by the list search text,
+// Name is either a module field or alias.field of a join.
+type ModuleActionSearchField struct {
+	Name string     `json:"name"`
+	Mode SearchMode `json:"mode"`
+}
+
+func (field ModuleActionSearchField) GetMode() SearchMode {
+	if len(field.Mode) == 0 {
+		return SearchModeContains
+	}
+	return field.Mode
+}
+
+// IsJoined reports whether the field belongs to a joined table.
+func (field ModuleActionSearchField) IsJoined() bool {
+	return strings.Contains(field.Name, ".")
+}
+
+// NewSearch returns search fields matched with the contains mode.
+func NewSearch(names ...string) []ModuleActionSearchField {
+	searchFields := make([]ModuleActionSearchField, 0, len(names))
+	for _, name := range names {
+		searchFields = append(searchFields, ModuleActionSearchField{
+			Name: name,
+			Mode: SearchModeContains,
+		})
+	}
+	return searchFields
+}
+
+const defaultFullTextLanguage = "simple"
+
+// ModuleActionFullTextSearch switches list search from LIKE matching to
+// postgres full-text search over a precomputed tsvector column or the
+// contains mode search fields of the parent table. Other search fields
+// keep their own mode and are matched alongside the full-text query.
+type ModuleActionFullTextSearch struct {
+	Language     string   `json:"language"`
+	VectorColumn string   `json:"vector_column,omitempty"`
+	Rank         bool     `json:"rank"`
+	Highlight    []string `json:"highlight,omitempty"`
+}
+
+func (search ModuleActionFullTextSearch) GetLanguage() string {
+	if len(search.Language) == 0 {
+		return defaultFullTextLanguage
+	}
+	return search.Language
 }
@@ -12,7 +12,10 @@ type UpdateModuleAction struct {
 	Fields       []string      `json:"fields"`
 	Permission   []string      `json:"permission"`
 	Auth         bool          `json:"auth"`
+	Strict       bool          `json:"strict"`
 	By           []interface{} `json:"by"`
+	// Where narrows the records which may be updated, a record outside of it is not found.
+	Where func(c *gin.Context) *ModuleActionWhere `json:"where"`
 }
 
 func (action UpdateModuleAction) Action() ModuleActionName {
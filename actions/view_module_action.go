@@ -8,13 +8,15 @@ type ViewModuleAction struct {
 	AfterAction  func(c *gin.Context)
 	Label        string `json:"label"`
 
-	Fields     []string           `json:"fields"`
-	Permission []string           `json:"permission"`
-	Auth       bool               `json:"auth"`
-	Join       []ModuleActionJoin `json:"join"`
-	Where      ModuleActionWhere  `json:"where"`
-	By         []interface{}      `json:"by"`
-	Extra      interface{}        `json:"extra"`
+	Fields     []string                                `json:"fields"`
+	Permission []string                                `json:"permission"`
+	Auth       bool                                    `json:"auth"`
+	Join       []ModuleActionJoin                      `json:"join"`
+	Where      func(c *gin.Context) *ModuleActionWhere `json:"where"`
+	By         []interface{}                           `json:"by"`
+	Extra      interface{}                             `json:"extra"`
+	// Relations are optional joins run only when named by the include query param.
+	Relations []ModuleActionJoin `json:"relations"`
 }
 
 func (action ViewModuleAction) Action() ModuleActionName {
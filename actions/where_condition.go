@@ -0,0 +1,209 @@
+package actions
+
+type WhereOperator string
+
+const (
+	WhereOperatorEq        WhereOperator = "eq"
+	WhereOperatorNe        WhereOperator = "ne"
+	WhereOperatorGt        WhereOperator = "gt"
+	WhereOperatorGte       WhereOperator = "gte"
+	WhereOperatorLt        WhereOperator = "lt"
+	WhereOperatorLte       WhereOperator = "lte"
+	WhereOperatorIn        WhereOperator = "in"
+	WhereOperatorNotIn     WhereOperator = "not_in"
+	WhereOperatorLike      WhereOperator = "like"
+	WhereOperatorILike     WhereOperator = "ilike"
+	WhereOperatorIsNull    WhereOperator = "is_null"
+	WhereOperatorIsNotNull WhereOperator = "is_not_null"
+	WhereOperatorAnd       WhereOperator = "and"
+	WhereOperatorOr        WhereOperator = "or"
+	WhereOperatorNot       WhereOperator = "not"
+	WhereOperatorRaw       WhereOperator = "raw"
+)
+
+// ModuleActionCondition is a node of a where condition tree.
+// Field is a module field or alias.field of a join, it is compared with Value
+// or with Values for in/not_in. And, Or and Not combine Conditions.
+// Raw is an sql fragment where every ? is replaced by the next of Args and ?? is a literal ?.
+type ModuleActionCondition struct {
+	Operator   WhereOperator           `json:"operator"`
+	Field      string                  `json:"field,omitempty"`
+	Value      interface{}             `json:"value,omitempty"`
+	Values     []interface{}           `json:"values,omitempty"`
+	Conditions []ModuleActionCondition `json:"conditions,omitempty"`
+	Raw        string                  `json:"raw,omitempty"`
+	Args       []interface{}           `json:"args,omitempty"`
+}
+
+func compare(operator WhereOperator, field string, value interface{}) ModuleActionCondition {
+	return ModuleActionCondition{
+		Operator: operator,
+		Field:    field,
+		Value:    value,
+	}
+}
+
+func Eq(field string, value interface{}) ModuleActionCondition {
+	return compare(WhereOperatorEq, field, value)
+}
+
+func Ne(field string, value interface{}) ModuleActionCondition {
+	return compare(WhereOperatorNe, field, value)
+}
+
+func Gt(field string, value interface{}) ModuleActionCondition {
+	return compare(WhereOperatorGt, field, value)
+}
+
+func Gte(field string, value interface{}) ModuleActionCondition {
+	return compare(WhereOperatorGte, field, value)
+}
+
+func Lt(field string, value interface{}) ModuleActionCondition {
+	return compare(WhereOperatorLt, field, value)
+}
+
+func Lte(field string, value interface{}) ModuleActionCondition {
+	return compare(WhereOperatorLte, field, value)
+}
+
+// Like matches the field with a LIKE pattern, the pattern is passed as is.
+func Like(field string, pattern string) ModuleActionCondition {
+	return compare(WhereOperatorLike, field, pattern)
+}
+
+// ILike is the case insensitive Like.
+func ILike(field string, pattern string) ModuleActionCondition {
+	return compare(WhereOperatorILike, field, pattern)
+}
+
+// In matches the field with any of values, no values match nothing.
+func In(field string, values ...interface{}) ModuleActionCondition {
+	return ModuleActionCondition{
+		Operator: WhereOperatorIn,
+		Field:    field,
+		Values:   values,
+	}
+}
+
+// NotIn matches the field with none of values, no values match everything.
+func NotIn(field string, values ...interface{}) ModuleActionCondition {
+	return ModuleActionCondition{
+		Operator: WhereOperatorNotIn,
+		Field:    field,
+		Values:   values,
+	}
+}
+
+func IsNull(field string) ModuleActionCondition {
+	return ModuleActionCondition{
+		Operator: WhereOperatorIsNull,
+		Field:    field,
+	}
+}
+
+func IsNotNull(field string) ModuleActionCondition {
+	return ModuleActionCondition{
+		Operator: WhereOperatorIsNotNull,
+		Field:    field,
+	}
+}
+
+// And matches when all of conditions match, no conditions match everything.
+func And(conditions ...ModuleActionCondition) ModuleActionCondition {
+	return ModuleActionCondition{
+		Operator:   WhereOperatorAnd,
+		Conditions: conditions,
+	}
+}
+
+// Or matches when any of conditions match, no conditions match nothing.
+func Or(conditions ...ModuleActionCondition) ModuleActionCondition {
+	return ModuleActionCondition{
+		Operator:   WhereOperatorOr,
+		Conditions: conditions,
+	}
+}
+
+func Not(condition ModuleActionCondition) ModuleActionCondition {
+	return ModuleActionCondition{
+		Operator:   WhereOperatorNot,
+		Conditions: []ModuleActionCondition{condition},
+	}
+}
+
+// Raw is an sql fragment such as `parent.created_ts > now() - interval '1 day'`,
+// every ? is bound to the next of args and ?? is a literal ?, e.g. the jsonb
+// `parent.tags ?? ?`. A fragment whose placeholders do not match args matches
+// no row. It must never contain request input.
+func Raw(query string, args ...interface{}) ModuleActionCondition {
+	return ModuleActionCondition{
+		Operator: WhereOperatorRaw,
+		Raw:      query,
+		Args:     args,
+	}
+}
+
+// WhereCondition wraps the condition tree into a where clause.
+func WhereCondition(conditions ...ModuleActionCondition) *ModuleActionWhere {
+	condition := And(conditions...)
+	if len(conditions) == 1 {
+		condition = conditions[0]
+	}
+	return &ModuleActionWhere{
+		Condition: &condition,
+	}
+}
+
+// GetCondition converts the where clause into one condition tree, legacy Fields
+// keep the sql precedence of AND over OR they were rendered with. nil is
+// returned when the clause has no conditions. Legacy Fields and Values of
+// different lengths can not be built and match no row, a broken scope never
+// widens to every row.
+func (where *ModuleActionWhere) GetCondition() *ModuleActionCondition {
+	if where == nil {
+		return nil
+	}
+
+	conditions := make([]ModuleActionCondition, 0, 10)
+	if len(where.Fields) != len(where.Values) {
+		// an empty OR renders as FALSE
+		conditions = append(conditions, Or())
+	} else if len(where.Fields) > 0 {
+		groups := make([]ModuleActionCondition, 0, 10)
+		group := make([]ModuleActionCondition, 0, 10)
+		for index, field := range where.Fields {
+			group = append(group, Eq(field.Name, where.Values[index]))
+			if field.ConditionType == ModuleActionWhereConditionTypeOR && index < len(where.Fields)-1 {
+				groups = append(groups, And(group...))
+				group = make([]ModuleActionCondition, 0, 10)
+			}
+		}
+		groups = append(groups, And(group...))
+
+		if len(groups) == 1 {
+			conditions = append(conditions, groups[0])
+		} else {
+			conditions = append(conditions, Or(groups...))
+		}
+	}
+
+	if where.Condition != nil {
+		conditions = append(conditions, *where.Condition)
+	}
+
+	for _, and := range where.And {
+		if condition := and.GetCondition(); condition != nil {
+			conditions = append(conditions, *condition)
+		}
+	}
+
+	if len(conditions) == 0 {
+		return nil
+	}
+	if len(conditions) == 1 {
+		return &conditions[0]
+	}
+	condition := And(conditions...)
+	return &condition
+}
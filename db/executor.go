@@ -16,11 +16,14 @@ type DBExecutor interface {
 		fields []fields.ModuleField,
 		page int64,
 		size int64,
-		searchFields []string,
+		searchFields []actions.ModuleActionSearchField,
 		searchText string,
-		filter map[string]string,
+		fullText *actions.ModuleActionFullTextSearch,
+		filter map[string]actions.ModuleActionFilter,
 		where *actions.ModuleActionWhere,
 		joins []actions.ModuleActionJoin,
+		sort []actions.ModuleActionSort,
+		queryFields []fields.ModuleField,
 	) (result []interface{}, rowsCount int64, err error)
 	View(
 		log *log.Entry,
@@ -32,8 +35,24 @@ type DBExecutor interface {
 		where *actions.ModuleActionWhere,
 		joins []actions.ModuleActionJoin,
 	) (interface{}, error)
-	Add(log *log.Entry, tableName string, primaryKey string, fields []fields.ModuleField, input map[string]interface{}) (interface{}, error)
-	Update(log *log.Entry, tableName string, primaryKey string, fields []fields.ModuleField, input map[string]interface{}, key interface{}, value interface{}) (interface{}, error)
-	Delete(log *log.Entry, tableName string, key interface{}, value interface{}) error
+	Aggregate(
+		log *log.Entry,
+		tableName string,
+		primaryKey string,
+		groupBy []string,
+		dateTrunc *actions.ModuleActionDateTrunc,
+		interval actions.DateTruncInterval,
+		metrics []actions.ModuleActionMetric,
+		searchFields []actions.ModuleActionSearchField,
+		searchText string,
+		fullText *actions.ModuleActionFullTextSearch,
+		filter map[string]actions.ModuleActionFilter,
+		where *actions.ModuleActionWhere,
+		joins []actions.ModuleActionJoin,
+		queryFields []fields.ModuleField,
+	) (result []interface{}, totals interface{}, err error)
+	Add(log *log.Entry, tableName string, primaryKey string, fields []fields.ModuleField, input map[string]interface{}) (result interface{}, primaryValue interface{}, err error)
+	Update(log *log.Entry, tableName string, primaryKey string, fields []fields.ModuleField, input map[string]interface{}, key interface{}, value interface{}, where *actions.ModuleActionWhere) (interface{}, error)
+	Delete(log *log.Entry, tableName string, key interface{}, value interface{}, where *actions.ModuleActionWhere) error
 	RawRequest(log *log.Entry, query string, params ...interface{}) (*sql.Rows, error)
 }
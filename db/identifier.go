@@ -0,0 +1,268 @@
+package db
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/portalenergy/pe-request-generator/actions"
+	"github.com/portalenergy/pe-request-generator/identifier"
+)
+
+// ErrInvalidIdentifier is returned when a table, column, alias or select
+// function can not be used in a query.
+var ErrInvalidIdentifier = identifier.ErrInvalid
+
+// parentAlias is the alias of the module table in every query.
+const parentAlias = "parent"
+
+// selectFunctions are the functions allowed as ModuleField.SelectFunction.
+var selectFunctions = map[string]bool{
+	"lower":         true,
+	"upper":         true,
+	"trim":          true,
+	"btrim":         true,
+	"length":        true,
+	"abs":           true,
+	"round":         true,
+	"ceil":          true,
+	"floor":         true,
+	"date":          true,
+	"to_json":       true,
+	"to_jsonb":      true,
+	"array_to_json": true,
+	"st_asgeojson":  true,
+	"st_astext":     true,
+}
+
+// RegisterSelectFunction allows an additional function as ModuleField.SelectFunction.
+func RegisterSelectFunction(name string) {
+	selectFunctions[strings.ToLower(name)] = true
+}
+
+// IsValidIdentifier reports whether name is a plain sql identifier.
+func IsValidIdentifier(name string) bool {
+	return identifier.IsValid(name)
+}
+
+func invalidIdentifier(kind string, name string) error {
+	return identifier.Invalid(kind, name)
+}
+
+// quoteIdentifier quotes names which passed IsValidIdentifier.
+func quoteIdentifier(name string) string {
+	return identifier.Quote(name)
+}
+
+func quoteTable(name string) string {
+	return identifier.QuoteTable(name)
+}
+
+func quoteColumn(alias string, name string) string {
+	return fmt.Sprintf(`%s.%s`, alias, quoteIdentifier(name))
+}
+
+// splitColumn splits alias.field into its alias and field, fields
+// without an alias belong to the parent table.
+func splitColumn(name string) (string, string) {
+	if dotIndex := strings.Index(name, "."); dotIndex >= 0 {
+		return name[:dotIndex], name[dotIndex+1:]
+	}
+	return parentAlias, name
+}
+
+func validateIdentifiers(kind string, names ...string) error {
+	return identifier.Validate(kind, names...)
+}
+
+// validateColumn accepts a parent field or alias.field of one of aliases.
+func validateColumn(name string, aliases map[string]bool) error {
+	alias, field := splitColumn(name)
+	if !aliases[alias] || !IsValidIdentifier(field) {
+		return invalidIdentifier("column", name)
+	}
+	return nil
+}
+
+func joinAliases(joins []actions.ModuleActionJoin) map[string]bool {
+	aliases := map[string]bool{
+		parentAlias: true,
+	}
+	for _, join := range joins {
+		aliases[join.ResultArrayName] = true
+	}
+	return aliases
+}
+
+func validateCondition(condition actions.ModuleActionCondition, aliases map[string]bool) error {
+	switch condition.Operator {
+	case actions.WhereOperatorAnd, actions.WhereOperatorOr, actions.WhereOperatorNot:
+		for _, child := range condition.Conditions {
+			if err := validateCondition(child, aliases); err != nil {
+				return err
+			}
+		}
+		return nil
+	case actions.WhereOperatorRaw:
+		return nil
+	}
+	return validateColumn(condition.Field, aliases)
+}
+
+func validateWhere(where *actions.ModuleActionWhere, aliases map[string]bool) error {
+	condition := where.GetCondition()
+	if condition == nil {
+		return nil
+	}
+	return validateCondition(*condition, aliases)
+}
+
+// Validate checks every identifier the query renders, nothing coming
+// from a module definition or a request is pasted into sql unchecked.
+func (pq *PostgresQuery) Validate() error {
+	if err := validateIdentifiers("table", pq.TableName); err != nil {
+		return err
+	}
+	if err := validateIdentifiers("column", pq.PrimaryKey); err != nil {
+		return err
+	}
+	if err := validateIdentifiers("column", pq.Fields...); err != nil {
+		return err
+	}
+	for field, selectFunction := range pq.FieldsFunction {
+		if !selectFunctions[strings.ToLower(selectFunction)] {
+			return invalidIdentifier("select function", selectFunction)
+		}
+		if err := validateIdentifiers("column", field); err != nil {
+			return err
+		}
+	}
+
+	declared := map[string]bool{
+		parentAlias: true,
+	}
+	for _, join := range pq.Joins {
+		if len(join.TableName) == 0 && len(join.Fields) == 0 {
+			continue
+		}
+		if !declared[joinParentAlias(join)] || (join.IsNested() && len(join.TableName) == 0) {
+			return invalidIdentifier("join parent", join.Parent)
+		}
+		if declared[join.ResultArrayName] {
+			return invalidIdentifier("join alias", join.ResultArrayName)
+		}
+		declared[join.ResultArrayName] = true
+		switch join.Shape {
+		case "", actions.JoinShapeArray, actions.JoinShapeObject:
+		default:
+			return invalidIdentifier("join shape", string(join.Shape))
+		}
+		if join.ResultArrayName == parentAlias {
+			return invalidIdentifier("join alias", join.ResultArrayName)
+		}
+		if err := validateIdentifiers("join alias", join.ResultArrayName); err != nil {
+			return err
+		}
+		if len(join.TableName) > 0 {
+			if err := validateIdentifiers("table", join.TableName); err != nil {
+				return err
+			}
+			if err := validateIdentifiers("column", join.OnParentKey, join.OnKey); err != nil {
+				return err
+			}
+		}
+		if err := validateIdentifiers("column", join.Fields...); err != nil {
+			return err
+		}
+		switch join.Type {
+		case "", actions.JoinTypeLeft, actions.JoinTypeLeftOuter, actions.JoinTypeRight, actions.JoinTypeRightOuter, actions.JoinTypeInner:
+		default:
+			return invalidIdentifier("join type", string(join.Type))
+		}
+	}
+
+	for field := range pq.Expressions {
+		if err := validateIdentifiers("column", field); err != nil {
+			return err
+		}
+	}
+
+	aliases := joinAliases(pq.Joins)
+	for _, sort := range pq.Sort {
+		if err := validateIdentifiers("column", sort.Name); err != nil {
+			return err
+		}
+	}
+	for _, field := range pq.SearchFields {
+		if err := validateColumn(field.Name, aliases); err != nil {
+			return err
+		}
+	}
+	for key := range pq.Filter {
+		if err := validateColumn(key, aliases); err != nil {
+			return err
+		}
+	}
+	if pq.FullText != nil {
+		if len(pq.FullText.VectorColumn) > 0 {
+			if err := validateIdentifiers("column", pq.FullText.VectorColumn); err != nil {
+				return err
+			}
+		}
+		if err := validateIdentifiers("column", pq.FullText.Highlight...); err != nil {
+			return err
+		}
+	}
+
+	return validateWhere(pq.Where, aliases)
+}
+
+// validateWrite checks the identifiers of insert, update and delete,
+// the where clause may only reference the parent table.
+func validateWrite(tableName string, key string, input map[string]interface{}, where *actions.ModuleActionWhere) error {
+	if err := validateIdentifiers("table", tableName); err != nil {
+		return err
+	}
+	if err := validateIdentifiers("column", key); err != nil {
+		return err
+	}
+	for column := range input {
+		if err := validateIdentifiers("column", column); err != nil {
+			return err
+		}
+	}
+	return validateWhere(where, joinAliases(nil))
+}
+
+// validateAggregate checks the identifiers of the aggregate query on top of Validate.
+func (pq *PostgresQuery) validateAggregate(groupBy []string, dateTrunc *actions.ModuleActionDateTrunc, interval actions.DateTruncInterval, metrics []actions.ModuleActionMetric) error {
+	if err := pq.Validate(); err != nil {
+		return err
+	}
+	if len(interval) > 0 && !(actions.ModuleActionDateTrunc{}).AllowInterval(interval) {
+		return invalidIdentifier("interval", string(interval))
+	}
+	if err := validateIdentifiers("column", groupBy...); err != nil {
+		return err
+	}
+	if dateTrunc != nil {
+		if err := validateIdentifiers("column", dateTrunc.Field); err != nil {
+			return err
+		}
+	}
+	for _, metric := range metrics {
+		switch metric.Function {
+		case actions.MetricFunctionSum, actions.MetricFunctionAvg, actions.MetricFunctionMin, actions.MetricFunctionMax, actions.MetricFunctionCount:
+		default:
+			return invalidIdentifier("metric function", string(metric.Function))
+		}
+		if err := validateIdentifiers("metric", metric.Name); err != nil {
+			return err
+		}
+		if len(metric.Field) > 0 {
+			if err := validateIdentifiers("column", metric.Field); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,152 @@
+package db
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/portalenergy/pe-request-generator/actions"
+)
+
+const fuzzPlaceholder = "zz_fuzz_placeholder"
+
+var identifierSeeds = []string{
+	"name",
+	"j.name",
+	"parent.name",
+	`name"; DROP TABLE stations; --`,
+	`j."name"`,
+	"name) OR (1=1",
+	"name/**/",
+	"j.name.extra",
+	".name",
+	"name.",
+	"unknown.name",
+	"имя",
+	"",
+	`"`,
+	"$1",
+}
+
+func fuzzQuery(filterKey string, where *actions.ModuleActionWhere, searchText string) PostgresQuery {
+	return PostgresQuery{
+		TableName:  "stations",
+		PrimaryKey: "id",
+		Fields:     []string{"name"},
+		SearchFields: []actions.ModuleActionSearchField{
+			{Name: "name"},
+		},
+		SearchText: searchText,
+		Filter: map[string]actions.ModuleActionFilter{
+			filterKey: actions.NewFilter("value"),
+		},
+		Joins: []actions.ModuleActionJoin{
+			actions.NewJoin("companies", actions.JoinTypeLeft, "company_id", "id", []string{"name"}, "j"),
+		},
+		Where: where,
+		Page:  0,
+		Size:  10,
+	}
+}
+
+// sameStructure checks that the query differs from the placeholder query
+// only by the quoted identifier.
+func sameStructure(t *testing.T, query string, baseline string, field string) {
+	expected := strings.ReplaceAll(baseline, quoteIdentifier(fuzzPlaceholder), quoteIdentifier(field))
+	if query != expected {
+		t.Fatalf("identifier %q changed the query structure:\n%s\n%s", field, query, expected)
+	}
+}
+
+func TestValidateRejectsInjection(t *testing.T) {
+	pq := fuzzQuery(`name"=1 OR "1`, nil, "")
+	if err := pq.Validate(); !errors.Is(err, ErrInvalidIdentifier) {
+		t.Fatalf("expected ErrInvalidIdentifier, got %v", err)
+	}
+
+	pq = fuzzQuery("name", nil, "")
+	pq.FieldsFunction = map[string]string{"name": "pg_sleep"}
+	if err := pq.Validate(); !errors.Is(err, ErrInvalidIdentifier) {
+		t.Fatalf("expected select function to be rejected, got %v", err)
+	}
+
+	pq = fuzzQuery("name", nil, "")
+	pq.Joins[0].OnParentKey = "company_id OR 1=1"
+	if err := pq.Validate(); !errors.Is(err, ErrInvalidIdentifier) {
+		t.Fatalf("expected join key to be rejected, got %v", err)
+	}
+}
+
+func FuzzFilterKey(f *testing.F) {
+	for _, seed := range identifierSeeds {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, key string) {
+		pq := fuzzQuery(key, nil, "")
+		if err := pq.Validate(); err != nil {
+			return
+		}
+
+		alias, field := splitColumn(key)
+		baselineKey := fuzzPlaceholder
+		if alias != parentAlias || strings.Contains(key, ".") {
+			baselineKey = alias + "." + fuzzPlaceholder
+		}
+		baseline := fuzzQuery(baselineKey, nil, "")
+
+		query, _ := pq.GetQuery(false)
+		baselineQuery, _ := baseline.GetQuery(false)
+		sameStructure(t, query, baselineQuery, field)
+	})
+}
+
+func FuzzWhereField(f *testing.F) {
+	for _, seed := range identifierSeeds {
+		f.Add(seed, "value")
+	}
+
+	f.Fuzz(func(t *testing.T, field string, value string) {
+		pq := fuzzQuery("name", actions.WhereCondition(actions.Or(actions.Eq(field, value), actions.In(field, value, value))), "")
+		if err := pq.Validate(); err != nil {
+			return
+		}
+		if strings.Contains(field, ".") {
+			return
+		}
+
+		baseline := fuzzQuery("name", actions.WhereCondition(actions.Or(actions.Eq(fuzzPlaceholder, "x"), actions.In(fuzzPlaceholder, "x", "x"))), "")
+		query, values := pq.GetQuery(false)
+		baselineQuery, _ := baseline.GetQuery(false)
+		sameStructure(t, query, baselineQuery, field)
+
+		if len(values) != 4 || values[0] != value {
+			t.Fatalf("values are not bound as parameters: %v", values)
+		}
+	})
+}
+
+func FuzzSearchText(f *testing.F) {
+	for _, seed := range []string{"name", "' OR 1=1 --", "%", "_", `\`, "$1", "x'); DROP TABLE stations; --"} {
+		f.Add(seed)
+	}
+
+	baseline := fuzzQuery("name", nil, "x")
+	baselineQuery, _ := baseline.GetQuery(false)
+
+	f.Fuzz(func(t *testing.T, text string) {
+		if len(text) == 0 {
+			return
+		}
+
+		pq := fuzzQuery("name", nil, text)
+		if err := pq.Validate(); err != nil {
+			t.Fatalf("search text must not affect validation: %v", err)
+		}
+
+		query, _ := pq.GetQuery(false)
+		if query != baselineQuery {
+			t.Fatalf("search text %q changed the query:\n%s", text, query)
+		}
+	})
+}
@@ -0,0 +1,161 @@
+package db
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/portalenergy/pe-request-generator/actions"
+	"github.com/portalenergy/pe-request-generator/fields"
+)
+
+// joinParentAlias returns the alias the join is joined on.
+func joinParentAlias(join actions.ModuleActionJoin) string {
+	if join.IsNested() {
+		return join.Parent
+	}
+	return parentAlias
+}
+
+// childJoins returns the joins nested in the join aliased alias.
+func childJoins(alias string, joins []actions.ModuleActionJoin) []actions.ModuleActionJoin {
+	children := make([]actions.ModuleActionJoin, 0, len(joins))
+	for _, join := range joins {
+		if join.IsNested() && join.Parent == alias {
+			children = append(children, join)
+		}
+	}
+	return children
+}
+
+// selectedJoins returns the joins which have a json_agg column: joins with
+// fields and joins holding nested joins.
+func selectedJoins(joins []actions.ModuleActionJoin) []actions.ModuleActionJoin {
+	selected := make([]actions.ModuleActionJoin, 0, len(joins))
+	for _, join := range joins {
+		if len(join.TableName) == 0 && len(join.Fields) == 0 {
+			continue
+		}
+		if len(join.Fields) > 0 || len(childJoins(join.ResultArrayName, joins)) > 0 {
+			selected = append(selected, join)
+		}
+	}
+	return selected
+}
+
+// joinColumns returns the columns aggregated for the join: its fields followed by
+// the hidden link keys which attach nested rows to the rows of their parent join.
+func joinColumns(join actions.ModuleActionJoin, joins []actions.ModuleActionJoin) []string {
+	columns := make([]string, 0, len(join.Fields)+2)
+	columns = append(columns, join.Fields...)
+	if join.IsNested() {
+		columns = append(columns, join.OnKey)
+	}
+	for _, child := range childJoins(join.ResultArrayName, joins) {
+		columns = append(columns, child.OnParentKey)
+	}
+	return columns
+}
+
+// shapeRows returns the rows of a join in its output shape.
+func shapeRows(join actions.ModuleActionJoin, rows []map[string]interface{}) interface{} {
+	if join.Shape != actions.JoinShapeObject {
+		return rows
+	}
+	if len(rows) == 0 {
+		return nil
+	}
+	return rows[0]
+}
+
+// nestRows assembles the output rows of the join from the decoded rows of every
+// join: nested joins are attached by their link keys and hidden keys are dropped.
+// match selects the rows belonging to one row of the parent join.
+func nestRows(
+	join actions.ModuleActionJoin,
+	joins []actions.ModuleActionJoin,
+	rowsByAlias map[string][]map[string]interface{},
+	match func(row map[string]interface{}) bool,
+) []map[string]interface{} {
+	children := childJoins(join.ResultArrayName, joins)
+	result := make([]map[string]interface{}, 0, 10)
+	for _, row := range rowsByAlias[join.ResultArrayName] {
+		if match != nil && !match(row) {
+			continue
+		}
+
+		output := make(map[string]interface{})
+		for _, field := range join.Fields {
+			output[field] = row[field]
+		}
+		for _, child := range children {
+			linkValue := row[child.OnParentKey]
+			onKey := child.OnKey
+			childRows := nestRows(child, joins, rowsByAlias, func(childRow map[string]interface{}) bool {
+				return linkValue != nil && reflect.DeepEqual(childRow[onKey], linkValue)
+			})
+			output[child.ResultArrayName] = shapeRows(child, childRows)
+		}
+		result = append(result, output)
+	}
+	return result
+}
+
+var joinAggregateFunctions = map[fields.AggregateFunction]string{
+	fields.AggregateFunctionCount: "COUNT",
+	fields.AggregateFunctionSum:   "SUM",
+	fields.AggregateFunctionAvg:   "AVG",
+	fields.AggregateFunctionMin:   "MIN",
+	fields.AggregateFunctionMax:   "MAX",
+}
+
+func validateJoinAggregates(moduleFields []fields.ModuleField) error {
+	for _, field := range moduleFields {
+		aggregate := field.JoinAggregate
+		if aggregate == nil {
+			continue
+		}
+
+		if _, ok := joinAggregateFunctions[aggregate.Function]; !ok {
+			return invalidIdentifier("aggregate function", string(aggregate.Function))
+		}
+		if len(aggregate.Field) == 0 && aggregate.Function != fields.AggregateFunctionCount {
+			return invalidIdentifier("aggregate field", aggregate.Field)
+		}
+		if err := validateIdentifiers("table", aggregate.TableName); err != nil {
+			return err
+		}
+		if err := validateIdentifiers("column", field.Name, aggregate.OnParentKey, aggregate.OnKey); err != nil {
+			return err
+		}
+		if len(aggregate.Field) > 0 {
+			if err := validateIdentifiers("column", aggregate.Field); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// joinAggregateExpression renders the join aggregate of the field as a correlated subquery.
+func joinAggregateExpression(name string, aggregate fields.JoinAggregate) string {
+	alias := fmt.Sprintf(`%s_aggregate`, name)
+	column := `*`
+	if len(aggregate.Field) > 0 {
+		column = quoteColumn(alias, aggregate.Field)
+	}
+
+	expression := fmt.Sprintf(
+		`SELECT %s(%s) FROM %s AS %s WHERE %s=%s`,
+		joinAggregateFunctions[aggregate.Function],
+		column,
+		quoteTable(aggregate.TableName),
+		alias,
+		quoteColumn(alias, aggregate.OnKey),
+		quoteColumn(parentAlias, aggregate.OnParentKey),
+	)
+	switch aggregate.Function {
+	case fields.AggregateFunctionCount, fields.AggregateFunctionSum:
+		return fmt.Sprintf(`COALESCE((%s), 0)`, expression)
+	}
+	return fmt.Sprintf(`(%s)`, expression)
+}
@@ -0,0 +1,132 @@
+package db
+
+import (
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/portalenergy/pe-request-generator/actions"
+	"github.com/portalenergy/pe-request-generator/fields"
+	"github.com/sirupsen/logrus"
+)
+
+func TestJoinAggregateFields(t *testing.T) {
+	moduleFields := []fields.ModuleField{
+		{Name: "name"},
+		{Name: "connectors_count", JoinAggregate: fields.NewJoinCount("connectors", "id", "station_id")},
+		{Name: "energy", JoinAggregate: fields.NewJoinAggregate(fields.AggregateFunctionSum, "sessions", "id", "station_id", "kwh")},
+	}
+	if err := validateJoinAggregates(moduleFields); err != nil {
+		t.Fatal(err)
+	}
+
+	_, names, functions, expressions := selectFields(moduleFields)
+	pq := PostgresQuery{
+		TableName:      "stations",
+		PrimaryKey:     "id",
+		Fields:         names,
+		FieldsFunction: functions,
+		Expressions:    expressions,
+		Filter:         map[string]actions.ModuleActionFilter{"connectors_count": actions.NewFilter("2")},
+		Sort:           []actions.ModuleActionSort{{Name: "energy", Desc: true}},
+		Size:           10,
+	}
+	if err := pq.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	query, _ := pq.GetQuery(false)
+	count := `COALESCE((SELECT COUNT(*) FROM public."connectors" AS connectors_count_aggregate WHERE connectors_count_aggregate."station_id"=parent."id"), 0)`
+	energy := `COALESCE((SELECT SUM(energy_aggregate."kwh") FROM public."sessions" AS energy_aggregate WHERE energy_aggregate."station_id"=parent."id"), 0)`
+	for _, expected := range []string{
+		`(` + count + `)=$1`,
+		`ORDER BY (` + energy + `) DESC`,
+	} {
+		if !strings.Contains(query, expected) {
+			t.Fatalf("expected %s in %s", expected, query)
+		}
+	}
+
+	moduleFields[2].JoinAggregate.Function = "sum); DROP TABLE stations; --"
+	if err := validateJoinAggregates(moduleFields); !errors.Is(err, ErrInvalidIdentifier) {
+		t.Fatalf("expected invalid aggregate function, got %v", err)
+	}
+}
+
+func TestUnselectedComputedFieldsFilterAndSort(t *testing.T) {
+	conn, fake := cannedDB(t,
+		cannedAnswer{match: "COUNT(parent.*)", columns: []string{"count"}, rows: [][]driver.Value{{int64(1)}, {int64(1)}}},
+		cannedAnswer{match: "SELECT", columns: []string{"id", "name"}},
+	)
+	queryFields := []fields.ModuleField{
+		{Name: "connectors_count", JoinAggregate: fields.NewJoinCount("connectors", "id", "station_id")},
+		{Name: "label", Expression: `upper(parent."name")`},
+	}
+	filter := map[string]actions.ModuleActionFilter{"connectors_count": actions.NewFilter("2")}
+	sort := []actions.ModuleActionSort{{Name: "label"}}
+	entry := logrus.NewEntry(logrus.New())
+
+	_, count, err := conn.List(entry, "stations", "id", []fields.ModuleField{{Name: "name"}}, 0, 10, nil, "", nil, filter, nil, nil, sort, queryFields)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 2 {
+		t.Fatalf("expected the count query to be answered, got %d", count)
+	}
+	if _, _, err := conn.Aggregate(entry, "stations", "id", nil, nil, "", []actions.ModuleActionMetric{{Name: "total", Function: actions.MetricFunctionCount}}, nil, "", nil, filter, nil, nil, queryFields); err != nil {
+		t.Fatal(err)
+	}
+
+	countFilter := `(COALESCE((SELECT COUNT(*) FROM public."connectors" AS connectors_count_aggregate WHERE connectors_count_aggregate."station_id"=parent."id"), 0))=$1`
+	for _, query := range fake.queries {
+		if !strings.Contains(query, countFilter) {
+			t.Fatalf("expected the unselected aggregate to be filtered by its expression, got %s", query)
+		}
+		if strings.HasPrefix(query, `SELECT parent."id"`) && !strings.Contains(query, `ORDER BY (upper(parent."name"))`) {
+			t.Fatalf("expected the unselected expression to be sorted by, got %s", query)
+		}
+		if strings.Contains(query, `SELECT parent."id", parent."name", (`) {
+			t.Fatalf("expected the query fields to stay unselected, got %s", query)
+		}
+	}
+	if len(fake.queries) != 4 {
+		t.Fatalf("expected list, count, aggregate and totals queries, got %v", fake.queries)
+	}
+}
+
+func TestAggregateJoinsOnlyPickParentRows(t *testing.T) {
+	pq := PostgresQuery{
+		TableName:  "stations",
+		PrimaryKey: "id",
+		Joins: []actions.ModuleActionJoin{{
+			TableName:       "connectors",
+			Type:            actions.JoinTypeInner,
+			OnParentKey:     "id",
+			OnKey:           "station_id",
+			ResultArrayName: "connectors",
+		}},
+		Filter: map[string]actions.ModuleActionFilter{"connectors.type": actions.NewFilter("ccs")},
+	}
+	metrics := []actions.ModuleActionMetric{{Name: "power", Function: actions.MetricFunctionSum, Field: "power"}}
+	if err := pq.validateAggregate([]string{"city"}, nil, "", metrics); err != nil {
+		t.Fatal(err)
+	}
+
+	query, values := pq.GetAggregateQuery([]string{"city"}, nil, "", metrics, false)
+	expected := `SELECT parent."city" AS "city", SUM(parent."power") AS "power" FROM public."stations" AS parent ` +
+		`WHERE parent."id" IN (SELECT parent."id" FROM public."stations" AS parent INNER JOIN public."connectors" AS connectors ` +
+		`ON parent."id"=connectors."station_id" WHERE (connectors."type"=$1)) GROUP BY 1 ORDER BY 1`
+	if query != expected {
+		t.Fatalf("unexpected aggregate query:\n%s\n%s", query, expected)
+	}
+	if len(values) != 1 || values[0] != "ccs" {
+		t.Fatalf("unexpected aggregate values: %v", values)
+	}
+
+	pq.Joins = nil
+	pq.Filter = map[string]actions.ModuleActionFilter{"city": actions.NewFilter("Almaty")}
+	if query, _ := pq.GetAggregateQuery(nil, nil, "", metrics, true); strings.Contains(query, " IN (") {
+		t.Fatalf("expected no subquery without joins, got %s", query)
+	}
+}
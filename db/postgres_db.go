@@ -4,18 +4,27 @@ import (
 	"database/sql"
 	"database/sql/driver"
 	"encoding/json"
-	"errors"
 	"fmt"
 	"sort"
 	"strings"
-	"time"
 
-	_ "github.com/lib/pq"
+	lpq "github.com/lib/pq"
 	"github.com/portalenergy/pe-request-generator/actions"
+	"github.com/portalenergy/pe-request-generator/errs"
 	"github.com/portalenergy/pe-request-generator/fields"
 	log "github.com/sirupsen/logrus"
 )
 
+// ErrNotFound is returned when the requested record does not exist
+// or is excluded by the where clause.
+var ErrNotFound = errs.ErrNotFound
+
+// ErrOutOfScope is returned when the updated values no longer match the where clause.
+var ErrOutOfScope = errs.ErrOutOfScope
+
+// highlightResultName is the row key holding full-text search snippets.
+const highlightResultName = "_highlight"
+
 type DB struct {
 	DBExecutor
 	sql *sql.DB
@@ -53,34 +62,45 @@ func (db *DB) List(
 	fields []fields.ModuleField,
 	page int64,
 	size int64,
-	searchFields []string,
+	searchFields []actions.ModuleActionSearchField,
 	searchText string,
-	filter map[string]string,
+	fullText *actions.ModuleActionFullTextSearch,
+	filter map[string]actions.ModuleActionFilter,
 	where *actions.ModuleActionWhere,
 	joins []actions.ModuleActionJoin,
+	sort []actions.ModuleActionSort,
+	queryFields []fields.ModuleField,
 ) (result []interface{}, rowsCount int64, err error) {
-	fieldsString := make([]string, 0, 10)
-	fieldsFunction := make(map[string]string)
-	for _, field := range fields {
-		fieldsString = append(fieldsString, field.Name)
-		if field.SelectFunction != nil {
-			fieldsFunction[field.Name] = *field.SelectFunction
-		}
-	}
+	joins, relations := splitRelations(joins)
+	fields, hiddenKeys := relationKeyFields(fields, primaryKey, relations)
+	_, fieldsString, fieldsFunction, fieldsExpression := selectFields(fields)
+	fieldsExpression = queryExpressions(fieldsExpression, queryFields)
 
 	pq := PostgresQuery{
 		TableName:      tableName,
 		PrimaryKey:     primaryKey,
 		Fields:         fieldsString,
 		FieldsFunction: fieldsFunction,
+		Expressions:    fieldsExpression,
 		SearchFields:   searchFields,
 		SearchText:     searchText,
+		FullText:       fullText,
 		Filter:         filter,
 		Joins:          joins,
 		Where:          where,
+		Sort:           sort,
 		Page:           page,
 		Size:           size,
 	}
+	if err = pq.Validate(); err != nil {
+		return nil, 0, err
+	}
+	if err = validateJoinAggregates(fields); err != nil {
+		return nil, 0, err
+	}
+	if err = validateJoinAggregates(queryFields); err != nil {
+		return nil, 0, err
+	}
 	query, values := pq.GetQuery(false)
 	countQuery, _ := pq.GetQuery(true)
 
@@ -107,123 +127,11 @@ func (db *DB) List(
 	}
 	defer rows.Close()
 
-	results := make([]interface{}, 0, 10)
-	for rows.Next() {
-		columnValues := make([]interface{}, 0, 10)
-		var primaryValue interface{}
-		columnValues = append(columnValues, &primaryValue)
-
-		for i := 0; i < len(fields); i++ {
-			value := fields[i].ScanObject
-			columnValues = append(columnValues, value)
-		}
-		for _, join := range joins {
-			if len(join.Fields) == 0 {
-				continue
-			}
-			var columnValue json.RawMessage
-			columnValues = append(columnValues, &columnValue)
-		}
-
-		err = rows.Scan(columnValues...)
-		if err != nil {
-			fmt.Println("SCAN ERR: ", err)
-			continue
-		}
-
-		currentResult := make(map[string]interface{})
-		offset := 1
-		for index, field := range fields {
-			value, ok := columnValues[index+offset].(driver.Valuer)
-
-			if ok {
-				if field.ResultValueConverter != nil {
-					currentResult[field.Name] = field.ResultValueConverter(value)
-				} else {
-					currentResult[field.Name], _ = value.Value()
-				}
-			} else {
-				if field.ResultValueConverter != nil {
-					currentResult[field.Name] = field.ResultValueConverter(value)
-				} else {
-					currentResult[field.Name] = value
-				}
-			}
-		}
-
-		if len(fields) > 0 {
-			offset = offset + len(fields)
-		}
-
-		for index, join := range joins {
-			joinValue := columnValues[index+offset]
-			converted, ok := joinValue.(*json.RawMessage)
-			if !ok {
-				continue
-			}
-
-			var joinValues [][]interface{}
-			err := json.Unmarshal(*converted, &joinValues)
-			if err != nil {
-				log.Errorln("VIEW JOIN ERR: ", err)
-				continue
-			}
-
-			checkString := ""
-			for _, val := range joinValues {
-				if val == nil {
-					continue
-				}
-
-				for _, v := range val {
-					if v == nil {
-						continue
-					}
-					checkString = fmt.Sprintf("%v%v", checkString, v)
-				}
-			}
-
-			joinResults := make([]map[string]interface{}, 0, 10)
-
-			if len(checkString) > 0 {
-				for _, joinValue := range joinValues {
-					resultMap := make(map[string]interface{})
-					for index, field := range join.Fields {
-						resultMap[field] = joinValue[index]
-					}
-					joinResults = append(joinResults, resultMap)
-				}
-				log.Infoln("VIEW JOIN RESULTS: ", joinResults)
-			}
-
-			joinStringsArray := make([]string, 0, 10)
-			for _, res := range joinResults {
-				jsonRes, err := json.Marshal(res)
-				if err != nil {
-					continue
-				}
-
-				joinStringsArray = append(joinStringsArray, string(jsonRes))
-			}
-			resultUnique := removeDuplicate(joinStringsArray)
-
-			joinResultUnique := make([]map[string]interface{}, 0, 10)
-			for _, res := range resultUnique {
-				var mapResult map[string]interface{}
-				err := json.Unmarshal([]byte(res), &mapResult)
-				if err != nil {
-					continue
-				}
-
-				joinResultUnique = append(joinResultUnique, mapResult)
-			}
-
-			currentResult[join.ResultArrayName] = joinResultUnique
-			//offset += 1
-		}
-
-		results = append(results, currentResult)
+	results, primaryValues := newRowMaterializer(log, fields, joins, pq.highlightFields()).scanRows(rows)
+	if err = db.loadRelations(log, primaryKey, results, primaryValues, relations); err != nil {
+		return nil, 0, err
 	}
+	stripFields(results, hiddenKeys)
 
 	result = append(result, results...)
 
@@ -257,33 +165,27 @@ func (db *DB) View(
 	where *actions.ModuleActionWhere,
 	joins []actions.ModuleActionJoin,
 ) (interface{}, error) {
-	fieldsString := make([]string, 0, 10)
-	fieldsFunction := make(map[string]string)
-	for _, field := range fields {
-		fieldsString = append(fieldsString, field.Name)
-		if field.SelectFunction != nil {
-			fieldsFunction[field.Name] = *field.SelectFunction
-		}
-	}
+	joins, relations := splitRelations(joins)
+	fields, hiddenKeys := relationKeyFields(fields, primaryKey, relations)
+	_, fieldsString, fieldsFunction, fieldsExpression := selectFields(fields)
 
 	//fmt.Println("SSSSSSSSSSSSS")
 	//fmt.Printf("\n\n\nWhere 1 TEST: %+v\n\n\n", where)
 	//fmt.Printf("\n\n\nWhere keys TEST: %+v\n\n\n", keys)
 
+	// the key always applies, a where of the action or the module policy only narrows it
+	keyWhere := &actions.ModuleActionWhere{
+		Fields: make([]actions.ModuleActionWhereField, 0, 10),
+		Values: make([]interface{}, 0, 10),
+	}
 	for index, key := range keys {
-		if where == nil || len(where.Fields) == 0 {
-			where = &actions.ModuleActionWhere{
-				Fields: make([]actions.ModuleActionWhereField, 0, 10),
-				Values: make([]interface{}, 0, 10),
-			}
-			where.Fields = append(where.Fields, actions.ModuleActionWhereField{
-				Name:          key.(string),
-				ConditionType: actions.ModuleActionWhereConditionTypeAnd,
-			})
-			where.Values = append(where.Values, values[index])
-		}
-
+		keyWhere.Fields = append(keyWhere.Fields, actions.ModuleActionWhereField{
+			Name:          key.(string),
+			ConditionType: actions.ModuleActionWhereConditionTypeAnd,
+		})
+		keyWhere.Values = append(keyWhere.Values, values[index])
 	}
+	where = actions.AndWhere(keyWhere, where)
 
 	//fmt.Printf("\n\n\nWhere 2 TEST: %+v\n\n\n", where)
 
@@ -292,6 +194,7 @@ func (db *DB) View(
 		PrimaryKey:     primaryKey,
 		Fields:         fieldsString,
 		FieldsFunction: fieldsFunction,
+		Expressions:    fieldsExpression,
 		SearchFields:   nil,
 		SearchText:     "",
 		Filter:         nil,
@@ -301,6 +204,12 @@ func (db *DB) View(
 		Size:           1,
 	}
 	where = nil
+	if err := pq.Validate(); err != nil {
+		return nil, err
+	}
+	if err := validateJoinAggregates(fields); err != nil {
+		return nil, err
+	}
 	query, values := pq.GetQuery(false)
 	log.Infoln("VIEW QUERY: ", query)
 	fmt.Println("VIEW QUERY: ", query)
@@ -319,110 +228,97 @@ func (db *DB) View(
 	}
 	defer rows.Close()
 
-	results := make([]interface{}, 0, 10)
-	for rows.Next() {
-		columnValues := make([]interface{}, 0, 10)
-		var primaryValue interface{}
-		columnValues = append(columnValues, &primaryValue)
-
-		for i := 0; i < len(fields); i++ {
-			value := fields[i].ScanObject
-			columnValues = append(columnValues, value)
-		}
-		for _, join := range joins {
-			if len(join.Fields) == 0 {
-				continue
-			}
-			var columnValue json.RawMessage
-			columnValues = append(columnValues, &columnValue)
-		}
-
-		err = rows.Scan(columnValues...)
-		if err != nil {
-			fmt.Println("ERROR: ", err)
-			continue
-		}
-
-		currentResult := make(map[string]interface{})
-		offset := 1
-		for index, field := range fields {
-			value, ok := columnValues[index+offset].(driver.Valuer)
-			if ok {
-				currentResult[field.Name], _ = value.Value()
-			} else {
-				currentResult[field.Name] = value
-			}
-		}
-
-		if len(fields) > 0 {
-			offset = offset + len(fields)
-		}
-
-		for index, join := range joins {
-			joinValue := columnValues[index+offset]
-			converted, ok := joinValue.(*json.RawMessage)
-			if !ok {
-				continue
-			}
-
-			var joinValues [][]interface{}
-			err := json.Unmarshal(*converted, &joinValues)
-			if err != nil {
-				log.Errorln("VIEW JOIN ERR: ", err)
-				continue
-			}
+	results, primaryValues := newRowMaterializer(log, fields, joins, nil).scanRows(rows)
+	if err = db.loadRelations(log, primaryKey, results, primaryValues, relations); err != nil {
+		return nil, err
+	}
+	stripFields(results, hiddenKeys)
 
-			log.Infoln("VIEW JOIN VALUES: ", joinValues, join)
+	fmt.Println("RESULTS:  ", results)
 
-			checkString := ""
-			for _, val := range joinValues {
-				if val == nil {
-					continue
-				}
+	if len(results) > 0 {
+		return results[0], nil
+	}
 
-				for _, v := range val {
-					if v == nil {
-						continue
-					}
-					checkString = fmt.Sprintf("%v%v", checkString, v)
-				}
-			}
+	return nil, ErrNotFound
+}
 
-			joinResults := make([]map[string]interface{}, 0, 10)
+func (db *DB) Aggregate(
+	log *log.Entry,
+	tableName string,
+	primaryKey string,
+	groupBy []string,
+	dateTrunc *actions.ModuleActionDateTrunc,
+	interval actions.DateTruncInterval,
+	metrics []actions.ModuleActionMetric,
+	searchFields []actions.ModuleActionSearchField,
+	searchText string,
+	fullText *actions.ModuleActionFullTextSearch,
+	filter map[string]actions.ModuleActionFilter,
+	where *actions.ModuleActionWhere,
+	joins []actions.ModuleActionJoin,
+	queryFields []fields.ModuleField,
+) (result []interface{}, totals interface{}, err error) {
+	pq := PostgresQuery{
+		TableName:    tableName,
+		PrimaryKey:   primaryKey,
+		Expressions:  queryExpressions(nil, queryFields),
+		SearchFields: searchFields,
+		SearchText:   searchText,
+		FullText:     fullText,
+		Filter:       filter,
+		Joins:        joins,
+		Where:        where,
+	}
+	if err = pq.validateAggregate(groupBy, dateTrunc, interval, metrics); err != nil {
+		return nil, nil, err
+	}
+	if err = validateJoinAggregates(queryFields); err != nil {
+		return nil, nil, err
+	}
+	query, values := pq.GetAggregateQuery(groupBy, dateTrunc, interval, metrics, false)
+	totalsQuery, _ := pq.GetAggregateQuery(groupBy, dateTrunc, interval, metrics, true)
 
-			if len(checkString) > 0 {
-				for _, joinValue := range joinValues {
-					resultMap := make(map[string]interface{})
-					for index, field := range join.Fields {
-						resultMap[field] = joinValue[index]
-					}
-					joinResults = append(joinResults, resultMap)
-				}
-				log.Infoln("VIEW JOIN RESULTS: ", joinResults)
-			}
+	log.Infoln("AGGREGATE QUERY: ", query)
+	log.Infoln("AGGREGATE TOTALS QUERY: ", totalsQuery)
 
-			currentResult[join.ResultArrayName] = joinResults
-			offset += 1
-		}
+	rows, err := db.sql.Query(query, values...)
+	if err != nil {
+		log.Errorln("AGGREGATE ERR: ", err)
+		return nil, nil, err
+	}
+	defer rows.Close()
 
-		results = append(results, currentResult)
+	result, err = scanMaps(rows)
+	if err != nil {
+		log.Errorln("AGGREGATE SCAN ERR: ", err)
+		return nil, nil, err
 	}
 
-	fmt.Println("RESULTS:  ", results)
+	totalsRows, err := db.sql.Query(totalsQuery, values...)
+	if err != nil {
+		log.Errorln("AGGREGATE TOTALS ERR: ", err)
+		return nil, nil, err
+	}
+	defer totalsRows.Close()
 
-	if len(results) > 0 {
-		return results[0], nil
+	totalsResult, err := scanMaps(totalsRows)
+	if err != nil {
+		log.Errorln("AGGREGATE TOTALS SCAN ERR: ", err)
+		return nil, nil, err
+	}
+	if len(totalsResult) > 0 {
+		totals = totalsResult[0]
 	}
 
-	return nil, errors.New("Record not found")
+	return result, totals, nil
 }
 
-func (db *DB) Add(log *log.Entry, tableName string, primaryKey string, fields []fields.ModuleField, input map[string]interface{}) (interface{}, error) {
-	query := fmt.Sprintf(`INSERT INTO public."%s"`, tableName)
-	output := struct {
-		Value      int64  `json:"value"`
-		PrimaryKey string `json:"primary_key"`
-	}{}
+func (db *DB) Add(log *log.Entry, tableName string, primaryKey string, fields []fields.ModuleField, input map[string]interface{}) (result interface{}, primaryValue interface{}, err error) {
+	if err = validateWrite(tableName, primaryKey, input, nil); err != nil {
+		return nil, nil, err
+	}
+	query := fmt.Sprintf(`INSERT INTO %s`, quoteTable(tableName))
 
 	keys := make([]string, 0, 10)
 	values := make([]interface{}, 0, 10)
@@ -437,12 +333,9 @@ func (db *DB) Add(log *log.Entry, tableName string, primaryKey string, fields []
 	for _, key := range sortedInput {
 		value, _ := input[key]
 		fieldsString = append(fieldsString, key)
-		keys = append(keys, fmt.Sprintf(`"%s"`, key))
+		keys = append(keys, quoteIdentifier(key))
 		values = append(values, value)
 	}
-	keys = append(keys, `"created_ts"`, `"updated_ts"`)
-	values = append(values, time.Now().Unix(), time.Now().Unix())
-
 	names := strings.Join(keys, ",")
 	valueNumbers := make([]string, 0, 10)
 
@@ -452,70 +345,165 @@ func (db *DB) Add(log *log.Entry, tableName string, primaryKey string, fields []
 
 	valueNumberString := strings.Join(valueNumbers, ",")
 
-	query = fmt.Sprintf(`%s (%s) VALUES (%s) RETURNING "%s"`, query, names, valueNumberString, primaryKey)
+	query = fmt.Sprintf(`%s (%s) VALUES (%s) RETURNING %s`, query, names, valueNumberString, quoteIdentifier(primaryKey))
 	log.Infoln("ADD QUERY: ", query)
 
 	fmt.Println(query)
 	fmt.Println(values)
 
-	err := db.sql.QueryRow(query, values...).Scan(&output.Value)
+	err = db.sql.QueryRow(query, values...).Scan(&primaryValue)
 	if err != nil {
 		fmt.Println("ERR: ", err)
 		log.Errorln("ADD ERR: ", err)
-		return nil, err
+		return nil, nil, err
 	}
 
-	output.PrimaryKey = primaryKey
-
-	//fmt.Println("PK: ", primaryKey, output.Value)
+	// uuid and other non-builtin types come back from the driver as raw bytes
+	if bytesValue, ok := primaryValue.([]byte); ok {
+		primaryValue = string(bytesValue)
+	}
 
-	return output, nil
+	result, err = db.View(log, tableName, primaryKey, fields, []interface{}{primaryKey}, []interface{}{primaryValue}, nil, nil)
+	if err != nil {
+		return nil, nil, err
+	}
 
-	//return db.View(log, tableName, primaryKey, fields, []interface{}{primaryKey}, []interface{}{value}, nil, nil)
+	return result, primaryValue, nil
 }
 
-func (db *DB) Update(log *log.Entry, tableName string, primaryKey string, fields []fields.ModuleField, input map[string]interface{}, key interface{}, value interface{}) (interface{}, error) {
-	query := fmt.Sprintf(`UPDATE public."%s" SET`, tableName)
-	values := make([]interface{}, 0, 10)
-	index := 1
-	fieldsString := make([]string, 0, 10)
-	for key, value := range input {
-		fieldsString = append(fieldsString, key)
-		query = fmt.Sprintf(`%s "%s" = $%d, `, query, key, index)
-		values = append(values, value)
-		index++
+// Update changes the record only when it also matches where, ErrNotFound is
+// returned when no record was changed. The changed records must still match
+// where, otherwise the update is rolled back with ErrOutOfScope so that input
+// can not move a record out of the scope it was updated under.
+func (db *DB) Update(log *log.Entry, tableName string, primaryKey string, fields []fields.ModuleField, input map[string]interface{}, key interface{}, value interface{}, where *actions.ModuleActionWhere) (interface{}, error) {
+	if err := validateWrite(tableName, fmt.Sprint(key), input, where); err != nil {
+		return nil, err
 	}
-	values = append(values, value)
-
-	query = strings.TrimSpace(query)
-	query = strings.TrimSuffix(query, ",")
-
-	query = fmt.Sprintf(`%s WHERE "%s"=$%d`, query, key, index)
+	if err := validateIdentifiers("column", primaryKey); err != nil {
+		return nil, err
+	}
+	query, values := updateQuery(tableName, primaryKey, input, key, value, where)
 
 	log.Infoln(`UPDATE QUERY: `, query)
 	log.Infoln(`UPDATE VALUES: `, values)
 
-	result, err := db.sql.Exec(query, values...)
+	tx, err := db.sql.Begin()
 	if err != nil {
 		return nil, err
 	}
+	defer tx.Rollback()
 
-	updatedCount, err := result.RowsAffected()
+	rows, err := tx.Query(query, values...)
+	if err != nil {
+		return nil, err
+	}
+	primaryValues := make([]interface{}, 0, 1)
+	for rows.Next() {
+		var primaryValue interface{}
+		if err = rows.Scan(&primaryValue); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		// uuid and other non-builtin types come back from the driver as raw bytes
+		if bytesValue, ok := primaryValue.([]byte); ok {
+			primaryValue = string(bytesValue)
+		}
+		primaryValues = append(primaryValues, primaryValue)
+	}
+	err = rows.Err()
+	rows.Close()
 	if err != nil {
 		return nil, err
 	}
 
-	if updatedCount == 0 {
-		return nil, errors.New("record not found")
+	if len(primaryValues) == 0 {
+		return nil, ErrNotFound
+	}
+
+	if scopeQuery, scopeValues := scopeCountQuery(tableName, primaryKey, primaryValues, where); len(scopeQuery) > 0 {
+		var scopedCount int
+		if err = tx.QueryRow(scopeQuery, scopeValues...).Scan(&scopedCount); err != nil {
+			return nil, err
+		}
+		if scopedCount != len(primaryValues) {
+			log.Errorln("UPDATE ERR: ", ErrOutOfScope)
+			return nil, ErrOutOfScope
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return db.View(log, tableName, primaryKey, fields, []interface{}{primaryKey}, []interface{}{primaryValues[0]}, nil, nil)
+}
+
+// updateQuery renders the update of the records matching key and where,
+// the primary keys of the changed records are returned.
+func updateQuery(tableName string, primaryKey string, input map[string]interface{}, key interface{}, value interface{}, where *actions.ModuleActionWhere) (string, []interface{}) {
+	sortedInput := make([]string, 0, len(input))
+	for inputKey := range input {
+		sortedInput = append(sortedInput, inputKey)
+	}
+	sort.Strings(sortedInput)
+
+	values := make([]interface{}, 0, len(input)+1)
+	index := 0
+	sets := make([]string, 0, len(input))
+	for _, inputKey := range sortedInput {
+		sets = append(sets, fmt.Sprintf(`%s = %s`, quoteIdentifier(inputKey), nextPlaceholder(input[inputKey], &index, &values)))
+	}
+
+	query := fmt.Sprintf(
+		`UPDATE %s AS %s SET %s WHERE %s=%s`,
+		quoteTable(tableName),
+		parentAlias,
+		strings.Join(sets, ", "),
+		quoteColumn(parentAlias, fmt.Sprint(key)),
+		nextPlaceholder(value, &index, &values),
+	)
+	if whereQuery := renderWhere(where, &index, &values); len(whereQuery) > 0 {
+		query = fmt.Sprintf(`%s AND %s`, query, whereQuery)
+	}
+	return fmt.Sprintf(`%s RETURNING %s`, query, quoteColumn(parentAlias, primaryKey)), values
+}
+
+// scopeCountQuery counts the records of primaryValues which match where,
+// the query is empty when there is no where to check.
+func scopeCountQuery(tableName string, primaryKey string, primaryValues []interface{}, where *actions.ModuleActionWhere) (string, []interface{}) {
+	values := make([]interface{}, 0, 10)
+	index := 0
+	keysPlaceholder := nextPlaceholder(lpq.Array(primaryValues), &index, &values)
+
+	whereQuery := renderWhere(where, &index, &values)
+	if len(whereQuery) == 0 {
+		return "", nil
 	}
 
-	return db.View(log, tableName, primaryKey, fields, []interface{}{key}, []interface{}{value}, nil, nil)
+	return fmt.Sprintf(
+		`SELECT count(*) FROM %s AS %s WHERE %s = ANY(%s) AND %s`,
+		quoteTable(tableName),
+		parentAlias,
+		quoteColumn(parentAlias, primaryKey),
+		keysPlaceholder,
+		whereQuery,
+	), values
 }
 
-func (db *DB) Delete(log *log.Entry, tableName string, key interface{}, value interface{}) error {
-	query := fmt.Sprintf(`DELETE FROM "%s" WHERE "%s"=$1`, tableName, key)
+// Delete removes the record only when it also matches where,
+// ErrNotFound is returned when no record was removed.
+func (db *DB) Delete(log *log.Entry, tableName string, key interface{}, value interface{}, where *actions.ModuleActionWhere) error {
+	if err := validateWrite(tableName, fmt.Sprint(key), nil, where); err != nil {
+		return err
+	}
+	query := fmt.Sprintf(`DELETE FROM %s AS %s WHERE %s=$1`, quoteTable(tableName), parentAlias, quoteColumn(parentAlias, fmt.Sprint(key)))
+	values := []interface{}{value}
+	index := 1
+	if whereQuery := renderWhere(where, &index, &values); len(whereQuery) > 0 {
+		query = fmt.Sprintf(`%s AND %s`, query, whereQuery)
+	}
 	log.Infoln("DELETE QUERY: ", query)
-	result, err := db.sql.Exec(query, value)
+	result, err := db.sql.Exec(query, values...)
 	if err != nil {
 		return err
 	}
@@ -527,24 +515,125 @@ func (db *DB) Delete(log *log.Entry, tableName string, key interface{}, value in
 
 	log.Infoln("DELETE COUNT OF DELETED: ", countOfDeleted)
 	if countOfDeleted == 0 {
-		return errors.New("record not found")
+		return ErrNotFound
 	}
 
 	return nil
 }
 
+// selectFields returns the fields read by the query with their names,
+// select functions and expressions, Go computed fields are left out.
+func selectFields(moduleFields []fields.ModuleField) ([]fields.ModuleField, []string, map[string]string, map[string]string) {
+	selected := make([]fields.ModuleField, 0, len(moduleFields))
+	names := make([]string, 0, len(moduleFields))
+	functions := make(map[string]string)
+	expressions := make(map[string]string)
+	for _, field := range moduleFields {
+		if !field.IsSelected() {
+			continue
+		}
+
+		selected = append(selected, field)
+		names = append(names, field.Name)
+		if field.JoinAggregate != nil {
+			expressions[field.Name] = joinAggregateExpression(field.Name, *field.JoinAggregate)
+		} else if len(field.Expression) > 0 {
+			expressions[field.Name] = field.Expression
+		} else if field.SelectFunction != nil {
+			functions[field.Name] = *field.SelectFunction
+		}
+	}
+	return selected, names, functions, expressions
+}
+
+// queryExpressions adds the expressions of the computed fields filtered or sorted
+// by without being selected, selected fields keep their own expression.
+func queryExpressions(expressions map[string]string, queryFields []fields.ModuleField) map[string]string {
+	if expressions == nil {
+		expressions = make(map[string]string)
+	}
+	_, _, _, queryExpressions := selectFields(queryFields)
+	for name, expression := range queryExpressions {
+		if _, ok := expressions[name]; !ok {
+			expressions[name] = expression
+		}
+	}
+	return expressions
+}
+
+// fieldValue converts a scanned column into the result value of the field.
+func fieldValue(field fields.ModuleField, scanned interface{}) interface{} {
+	if field.ResultValueConverter != nil {
+		return field.ResultValueConverter(scanned)
+	}
+
+	if value, ok := scanned.(driver.Valuer); ok {
+		result, _ := value.Value()
+		return result
+	}
+	return scanned
+}
+
+// computeFields sets the Go computed fields of the row,
+// in the order they are declared.
+func computeFields(moduleFields []fields.ModuleField, row map[string]interface{}) {
+	for _, field := range moduleFields {
+		if field.Compute != nil {
+			row[field.Name] = field.Compute(row)
+		}
+	}
+}
+
 func (db *DB) RawRequest(log *log.Entry, query string, params ...interface{}) (*sql.Rows, error) {
 	return db.sql.Query(query, params...)
 }
 
-func removeDuplicate(sliceList []string) []string {
-	allKeys := make(map[string]bool)
-	var list []string
-	for _, item := range sliceList {
-		if _, value := allKeys[item]; !value {
-			allKeys[item] = true
-			list = append(list, item)
+// scanMaps reads every row into a column name keyed map. numeric columns, which the
+// driver returns as text, keep their exact digits as json.Number, other text stays a string.
+func scanMaps(rows *sql.Rows) ([]interface{}, error) {
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	columnTypes, err := rows.ColumnTypes()
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]interface{}, 0, 10)
+	for rows.Next() {
+		columnValues := make([]interface{}, len(columns))
+		columnPointers := make([]interface{}, len(columns))
+		for index := range columnValues {
+			columnPointers[index] = &columnValues[index]
+		}
+
+		err = rows.Scan(columnPointers...)
+		if err != nil {
+			return nil, err
+		}
+
+		currentResult := make(map[string]interface{})
+		for index, column := range columns {
+			value := columnValues[index]
+			if bytesValue, ok := value.([]byte); ok {
+				if isNumericColumn(columnTypes[index].DatabaseTypeName()) {
+					value = json.Number(bytesValue)
+				} else {
+					value = string(bytesValue)
+				}
+			}
+			currentResult[column] = value
 		}
+
+		results = append(results, currentResult)
 	}
-	return list
+
+	return results, rows.Err()
+}
+
+// isNumericColumn reports whether the database type is numeric,
+// integer and float columns are already scanned as Go numbers.
+func isNumericColumn(typeName string) bool {
+	return typeName == "NUMERIC" || typeName == "DECIMAL"
 }
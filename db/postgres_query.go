@@ -2,8 +2,10 @@ package db
 
 import (
 	"fmt"
+	"sort"
 	"strings"
 
+	lpq "github.com/lib/pq"
 	"github.com/portalenergy/pe-request-generator/actions"
 )
 
@@ -12,130 +14,482 @@ type PostgresQuery struct {
 	PrimaryKey     string
 	Fields         []string
 	FieldsFunction map[string]string
-	SearchFields   []string
-	SearchText     string
-	Filter         map[string]string
-	Joins          []actions.ModuleActionJoin
-	Where          *actions.ModuleActionWhere
-	Page           int64
-	Size           int64
+	// Expressions are the sql expressions of computed fields by name.
+	Expressions  map[string]string
+	SearchFields []actions.ModuleActionSearchField
+	SearchText   string
+	FullText     *actions.ModuleActionFullTextSearch
+	Filter       map[string]actions.ModuleActionFilter
+	Joins        []actions.ModuleActionJoin
+	Where        *actions.ModuleActionWhere
+	Sort         []actions.ModuleActionSort
+	Page         int64
+	Size         int64
 }
 
 func (pq *PostgresQuery) GetQuery(isCount bool) (string, []interface{}) {
-	values := make([]interface{}, 0, 10)
+	conditions := pq.getConditions()
 
 	fields := make([]string, 0, 10)
-	fields = append(fields, fmt.Sprintf(`parent."%s"`, pq.PrimaryKey))
+	fields = append(fields, quoteColumn(parentAlias, pq.PrimaryKey))
 
 	fmt.Println("FIELDS: ", pq.Fields)
 	fmt.Println("FIELD FUN: ", pq.FieldsFunction)
 	for _, field := range pq.Fields {
 		selectFunction, ok := pq.FieldsFunction[field]
 
-		if !ok {
-			fields = append(fields, fmt.Sprintf(`parent."%s"`, field))
+		if expression, isExpression := pq.Expressions[field]; isExpression {
+			fields = append(fields, fmt.Sprintf(`(%s)`, expression))
+		} else if !ok {
+			fields = append(fields, quoteColumn(parentAlias, field))
 		} else {
-			fields = append(fields, fmt.Sprintf(`%s(parent."%s")`, selectFunction, field))
+			fields = append(fields, fmt.Sprintf(`%s(%s)`, selectFunction, quoteColumn(parentAlias, field)))
 		}
 	}
 	fmt.Println("FIELDS: ", fields)
 
-	for _, join := range pq.Joins {
-		if len(join.Fields) == 0 {
-			continue
-		}
-
+	joins := selectedJoins(pq.Joins)
+	for _, join := range joins {
 		joinQueries := make([]string, 0, 10)
-		for _, field := range join.Fields {
-			joinQueries = append(joinQueries, fmt.Sprintf(`%s."%s"`, join.ResultArrayName, field))
+		for _, field := range joinColumns(join, joins) {
+			joinQueries = append(joinQueries, quoteColumn(join.ResultArrayName, field))
 		}
 
 		joinQueryField := fmt.Sprintf(`json_agg(json_build_array(%s))`, strings.Join(joinQueries, ", "))
 		fields = append(fields, joinQueryField)
 	}
 
+	if len(conditions.searchQuery) > 0 {
+		for _, field := range pq.highlightFields() {
+			fields = append(fields, fmt.Sprintf(
+				`ts_headline($%d::regconfig, %s::text, %s)`,
+				conditions.languageIndex,
+				quoteColumn(parentAlias, field),
+				conditions.searchQuery,
+			))
+		}
+	}
+
 	queryFields := strings.Join(fields, ", ")
 	if isCount {
 		queryFields = `COUNT(parent.*)`
 	}
 
-	query := fmt.Sprintf(`SELECT %s FROM public."%s" AS parent`, queryFields, pq.TableName)
+	query := fmt.Sprintf(`SELECT %s %s`, queryFields, pq.getFrom())
+	if len(conditions.where) > 0 {
+		query = fmt.Sprintf(`%s WHERE %s`, query, conditions.where)
+	}
+
+	query = fmt.Sprintf(`%s GROUP BY %s`, query, quoteColumn(parentAlias, pq.PrimaryKey))
+	if isCount {
+		return query, conditions.values
+	}
+
+	orders := make([]string, 0, len(pq.Sort)+1)
+	for _, sort := range pq.Sort {
+		direction := "ASC"
+		if sort.Desc {
+			direction = "DESC"
+		}
+		orders = append(orders, fmt.Sprintf(`%s %s`, pq.fieldColumn(sort.Name), direction))
+	}
+	if len(conditions.searchQuery) > 0 && pq.FullText.Rank {
+		orders = append(orders, fmt.Sprintf(`ts_rank(%s, %s) DESC`, pq.searchVector(conditions.languageIndex), conditions.searchQuery))
+	}
+	if len(orders) > 0 {
+		query = fmt.Sprintf(`%s ORDER BY %s`, query, strings.Join(orders, ", "))
+	}
+
+	return fmt.Sprintf(`%s LIMIT %d OFFSET %d`, query, pq.Size, pq.Size*pq.Page), conditions.values
+}
+
+// hasJoins reports whether the query joins other tables.
+func (pq *PostgresQuery) hasJoins() bool {
+	for _, join := range pq.Joins {
+		if len(join.TableName) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func (pq *PostgresQuery) getFrom() string {
+	query := fmt.Sprintf(`FROM %s AS %s`, quoteTable(pq.TableName), parentAlias)
 	for _, join := range pq.Joins {
 		if len(join.TableName) > 0 {
 			query = fmt.Sprintf(
-				`%s %s JOIN public."%s" AS %s ON parent."%s"=%s."%s"`,
+				`%s %s JOIN %s AS %s ON %s=%s`,
 				query,
 				join.Type,
-				join.TableName,
+				quoteTable(join.TableName),
 				join.ResultArrayName,
-				join.OnParentKey,
-				join.ResultArrayName,
-				join.OnKey,
+				quoteColumn(joinParentAlias(join), join.OnParentKey),
+				quoteColumn(join.ResultArrayName, join.OnKey),
 			)
 		}
 	}
 
-	conditionIndex := 0
-	if pq.Where != nil {
-		if len(pq.Where.Fields) > 0 && len(pq.Where.Values) > 0 && len(pq.Where.Fields) == len(pq.Where.Values) {
-			values = pq.Where.Values
-			lastIndex := len(pq.Where.Fields) - 1
+	return query
+}
 
-			whereQueries := make([]string, 0, 10)
-			for index, whereKey := range pq.Where.Fields {
-				conditionIndex += 1
-				if lastIndex == index {
-					whereQueries = append(whereQueries, fmt.Sprintf(`parent.%s=$%d`, whereKey.Name, conditionIndex))
-				} else {
-					whereQueries = append(whereQueries, fmt.Sprintf(`parent.%s=$%d %s`, whereKey.Name, conditionIndex, whereKey.ConditionType))
-				}
+// renderWhere renders the where clause on the parent alias, placeholders
+// continue after index and the values are appended. Empty clauses render as "".
+func renderWhere(where *actions.ModuleActionWhere, index *int, values *[]interface{}) string {
+	condition := where.GetCondition()
+	if condition == nil {
+		return ""
+	}
+	return renderCondition(*condition, index, values)
+}
+
+var conditionOperators = map[actions.WhereOperator]string{
+	actions.WhereOperatorEq:    "=",
+	actions.WhereOperatorNe:    "<>",
+	actions.WhereOperatorGt:    ">",
+	actions.WhereOperatorGte:   ">=",
+	actions.WhereOperatorLt:    "<",
+	actions.WhereOperatorLte:   "<=",
+	actions.WhereOperatorLike:  "LIKE",
+	actions.WhereOperatorILike: "ILIKE",
+}
+
+// conditionColumn quotes a module field of the parent table or alias.field of a join.
+func conditionColumn(field string) string {
+	return quoteColumn(splitColumn(field))
+}
+
+func nextPlaceholder(value interface{}, index *int, values *[]interface{}) string {
+	*index += 1
+	*values = append(*values, value)
+	return fmt.Sprintf(`$%d`, *index)
+}
+
+// renderCondition renders the condition tree, every value is bound
+// as the next positional parameter.
+func renderCondition(condition actions.ModuleActionCondition, index *int, values *[]interface{}) string {
+	switch condition.Operator {
+	case actions.WhereOperatorAnd, actions.WhereOperatorOr:
+		if len(condition.Conditions) == 0 {
+			if condition.Operator == actions.WhereOperatorAnd {
+				return "TRUE"
 			}
+			return "FALSE"
+		}
 
-			query = fmt.Sprintf(`%s WHERE (%s)`, query, strings.Join(whereQueries, " "))
+		queries := make([]string, 0, len(condition.Conditions))
+		for _, child := range condition.Conditions {
+			queries = append(queries, renderCondition(child, index, values))
 		}
+		if len(queries) == 1 {
+			return queries[0]
+		}
+		return fmt.Sprintf(`(%s)`, strings.Join(queries, fmt.Sprintf(" %s ", strings.ToUpper(string(condition.Operator)))))
+	case actions.WhereOperatorNot:
+		return fmt.Sprintf(`NOT (%s)`, renderCondition(actions.And(condition.Conditions...), index, values))
+	case actions.WhereOperatorRaw:
+		return renderRaw(condition.Raw, condition.Args, index, values)
+	case actions.WhereOperatorIsNull:
+		return fmt.Sprintf(`%s IS NULL`, conditionColumn(condition.Field))
+	case actions.WhereOperatorIsNotNull:
+		return fmt.Sprintf(`%s IS NOT NULL`, conditionColumn(condition.Field))
+	case actions.WhereOperatorIn, actions.WhereOperatorNotIn:
+		if len(condition.Values) == 0 {
+			if condition.Operator == actions.WhereOperatorIn {
+				return "FALSE"
+			}
+			return "TRUE"
+		}
+
+		placeholders := make([]string, 0, len(condition.Values))
+		for _, value := range condition.Values {
+			placeholders = append(placeholders, nextPlaceholder(value, index, values))
+		}
+		operator := "IN"
+		if condition.Operator == actions.WhereOperatorNotIn {
+			operator = "NOT IN"
+		}
+		return fmt.Sprintf(`%s %s (%s)`, conditionColumn(condition.Field), operator, strings.Join(placeholders, ","))
 	}
 
-	if len(pq.SearchText) > 0 && len(pq.SearchFields) > 0 {
-		values = append(values, strings.ToLower(pq.SearchText))
+	operator, ok := conditionOperators[condition.Operator]
+	if !ok {
+		// an unknown operator must never widen the scope
+		return "FALSE"
+	}
+	return fmt.Sprintf(`%s %s %s`, conditionColumn(condition.Field), operator, nextPlaceholder(condition.Value, index, values))
+}
+
+// renderRaw binds every ? of the fragment to the next of args, ?? is a literal ?.
+// Placeholders which do not match args must never widen the scope.
+func renderRaw(raw string, args []interface{}, index *int, values *[]interface{}) string {
+	parts := make([]string, 0, len(args)+1)
+	var part strings.Builder
+	for position := 0; position < len(raw); position++ {
+		if raw[position] != '?' {
+			part.WriteByte(raw[position])
+			continue
+		}
+		if position+1 < len(raw) && raw[position+1] == '?' {
+			part.WriteByte('?')
+			position++
+			continue
+		}
+		parts = append(parts, part.String())
+		part.Reset()
+	}
+	parts = append(parts, part.String())
+
+	if len(parts)-1 != len(args) {
+		return "FALSE"
+	}
+
+	query := parts[0]
+	for argIndex, arg := range args {
+		query = fmt.Sprintf(`%s%s%s`, query, nextPlaceholder(arg, index, values), parts[argIndex+1])
+	}
+	return fmt.Sprintf(`(%s)`, query)
+}
+
+type postgresConditions struct {
+	where  string
+	values []interface{}
+	// searchQuery is the tsquery expression of a full-text search and
+	// languageIndex the placeholder of its text search configuration.
+	searchQuery   string
+	languageIndex int
+}
+
+// getConditions renders where, search and filter into one condition
+// with its positional values.
+func (pq *PostgresQuery) getConditions() postgresConditions {
+	result := postgresConditions{
+		values: make([]interface{}, 0, 10),
+	}
+	conditionQueries := make([]string, 0, 10)
+
+	conditionIndex := 0
+	if whereQuery := renderWhere(pq.Where, &conditionIndex, &result.values); len(whereQuery) > 0 {
+		conditionQueries = append(conditionQueries, whereQuery)
+	}
+
+	if len(pq.SearchText) > 0 && (len(pq.SearchFields) > 0 || pq.isFullTextSearch()) {
 		searchQueries := make([]string, 0, 10)
-		conditionIndex += 1
+		lowerIndex, textIndex := 0, 0
+
+		if pq.isFullTextSearch() {
+			result.values = append(result.values, pq.FullText.GetLanguage(), pq.SearchText)
+			conditionIndex += 2
+			result.languageIndex = conditionIndex - 1
+			result.searchQuery = fmt.Sprintf(`websearch_to_tsquery($%d::regconfig, $%d)`, conditionIndex-1, conditionIndex)
+			textIndex = conditionIndex
+
+			searchQueries = append(searchQueries, fmt.Sprintf(`%s @@ %s`, pq.searchVector(result.languageIndex), result.searchQuery))
+		}
 
 		for _, field := range pq.SearchFields {
-			searchQueries = append(searchQueries, fmt.Sprintf(`LOWER(parent."%s") LIKE '%%' || $%d || '%%'`, field, conditionIndex))
+			if pq.isFullTextField(field) {
+				continue
+			}
+
+			mode := field.GetMode()
+			if mode == actions.SearchModeTrigram {
+				if textIndex == 0 {
+					result.values = append(result.values, pq.SearchText)
+					conditionIndex += 1
+					textIndex = conditionIndex
+				}
+			} else if lowerIndex == 0 {
+				result.values = append(result.values, strings.ToLower(pq.SearchText))
+				conditionIndex += 1
+				lowerIndex = conditionIndex
+			}
+
+			column := searchColumn(field)
+			switch mode {
+			case actions.SearchModePrefix:
+				searchQueries = append(searchQueries, fmt.Sprintf(`LOWER(%s) LIKE $%d || '%%'`, column, lowerIndex))
+			case actions.SearchModeExact:
+				searchQueries = append(searchQueries, fmt.Sprintf(`LOWER(%s) = $%d`, column, lowerIndex))
+			case actions.SearchModeTrigram:
+				searchQueries = append(searchQueries, fmt.Sprintf(`%s::text %% $%d`, column, textIndex))
+			default:
+				searchQueries = append(searchQueries, fmt.Sprintf(`LOWER(%s) LIKE '%%' || $%d || '%%'`, column, lowerIndex))
+			}
 		}
 
-		if pq.Where != nil && len(pq.Where.Fields) > 0 && len(pq.Where.Values) > 0 && len(pq.Where.Fields) == len(pq.Where.Values) {
-			query = fmt.Sprintf(`%s AND (%s)`, query, strings.Join(searchQueries, " OR "))
-		} else {
-			query = fmt.Sprintf("%s WHERE (%s)", query, strings.Join(searchQueries, " OR "))
+		if len(searchQueries) > 0 {
+			conditionQueries = append(conditionQueries, fmt.Sprintf(`(%s)`, strings.Join(searchQueries, " OR ")))
 		}
 	}
 
 	if len(pq.Filter) > 0 {
+		keys := make([]string, 0, len(pq.Filter))
+		for key := range pq.Filter {
+			keys = append(keys, key)
+		}
+		// map order would renumber the placeholders on every request
+		sort.Strings(keys)
+
 		filterQueries := make([]string, 0, 10)
-		for key, value := range pq.Filter {
-			conditionIndex += 1
-			values = append(values, value)
-			result := strings.Split(key, ".")
-			if len(result) > 1 {
-				placeholder := result[0]
-				placeholderKey := result[1]
-				filterQueries = append(filterQueries, fmt.Sprintf(`%s."%s"=$%d`, placeholder, placeholderKey, conditionIndex))
-			} else {
-				filterQueries = append(filterQueries, fmt.Sprintf(`parent."%s"=$%d`, key, conditionIndex))
+		for _, key := range keys {
+			filter := pq.Filter[key]
+			column := pq.fieldColumn(key)
+			if len(filter.Values) == 1 {
+				filterQueries = append(filterQueries, fmt.Sprintf(`%s=%s`, column, nextPlaceholder(filter.Values[0], &conditionIndex, &result.values)))
+			} else if len(filter.Values) > 1 {
+				filterQueries = append(filterQueries, fmt.Sprintf(`%s = ANY(%s)`, column, nextPlaceholder(lpq.Array(filter.Values), &conditionIndex, &result.values)))
+			}
+			if filter.Min != nil {
+				filterQueries = append(filterQueries, fmt.Sprintf(`%s >= %s`, column, nextPlaceholder(filter.Min, &conditionIndex, &result.values)))
+			}
+			if filter.Max != nil {
+				filterQueries = append(filterQueries, fmt.Sprintf(`%s <= %s`, column, nextPlaceholder(filter.Max, &conditionIndex, &result.values)))
 			}
 		}
 
-		if (pq.Where != nil && len(pq.Where.Fields) > 0 && len(pq.Where.Values) > 0 && len(pq.Where.Fields) == len(pq.Where.Values)) || (len(pq.SearchText) > 0 && len(pq.SearchFields) > 0) {
-			query = fmt.Sprintf(`%s AND (%s)`, query, strings.Join(filterQueries, " AND "))
-		} else {
-			query = fmt.Sprintf(`%s WHERE (%s)`, query, strings.Join(filterQueries, " AND "))
+		if len(filterQueries) > 0 {
+			conditionQueries = append(conditionQueries, fmt.Sprintf(`(%s)`, strings.Join(filterQueries, " AND ")))
 		}
 	}
 
-	if isCount {
-		return fmt.Sprintf(`%s GROUP BY parent."%s"`, query, pq.PrimaryKey), values
+	result.where = strings.Join(conditionQueries, " AND ")
+
+	return result
+}
+
+// fieldColumn renders a filtered or sorted field, computed fields
+// use their expression and the rest are columns.
+func (pq *PostgresQuery) fieldColumn(name string) string {
+	if expression, ok := pq.Expressions[name]; ok {
+		return fmt.Sprintf(`(%s)`, expression)
+	}
+	return conditionColumn(name)
+}
+
+// highlightFields returns the fields selected as ts_headline snippets.
+func (pq *PostgresQuery) highlightFields() []string {
+	if !pq.isFullTextSearch() {
+		return nil
+	}
+	return pq.FullText.Highlight
+}
+
+func (pq *PostgresQuery) isFullTextSearch() bool {
+	if pq.FullText == nil || len(pq.SearchText) == 0 {
+		return false
+	}
+	if len(pq.FullText.VectorColumn) > 0 {
+		return true
+	}
+
+	for _, field := range pq.SearchFields {
+		if pq.isFullTextField(field) {
+			return true
+		}
 	}
+	return false
+}
 
-	return fmt.Sprintf(`%s GROUP BY parent."%s" LIMIT %d OFFSET %d`, query, pq.PrimaryKey, pq.Size, pq.Size*pq.Page), values
+// isFullTextField reports whether the search field is part of the full-text
+// vector instead of being matched by its own mode.
+func (pq *PostgresQuery) isFullTextField(field actions.ModuleActionSearchField) bool {
+	return pq.FullText != nil &&
+		len(pq.FullText.VectorColumn) == 0 &&
+		!field.IsJoined() &&
+		field.GetMode() == actions.SearchModeContains
+}
+
+// searchVector returns the tsvector expression searched by full-text mode,
+// languageIndex is the placeholder holding the text search configuration.
+func (pq *PostgresQuery) searchVector(languageIndex int) string {
+	if len(pq.FullText.VectorColumn) > 0 {
+		return quoteColumn(parentAlias, pq.FullText.VectorColumn)
+	}
+
+	columns := make([]string, 0, 10)
+	for _, field := range pq.SearchFields {
+		if pq.isFullTextField(field) {
+			columns = append(columns, searchColumn(field))
+		}
+	}
+
+	return fmt.Sprintf(`to_tsvector($%d::regconfig, concat_ws(' ', %s))`, languageIndex, strings.Join(columns, ", "))
+}
+
+func searchColumn(field actions.ModuleActionSearchField) string {
+	return conditionColumn(field.Name)
+}
+
+// GetAggregateQuery returns the grouped metrics query, or the totals query
+// over the same conditions when isTotals is set.
+func (pq *PostgresQuery) GetAggregateQuery(
+	groupBy []string,
+	dateTrunc *actions.ModuleActionDateTrunc,
+	interval actions.DateTruncInterval,
+	metrics []actions.ModuleActionMetric,
+	isTotals bool,
+) (string, []interface{}) {
+	conditions := pq.getConditions()
+
+	groups := make([]string, 0, 10)
+	if !isTotals {
+		for _, field := range groupBy {
+			groups = append(groups, quoteColumn(parentAlias, field))
+		}
+		if dateTrunc != nil && len(interval) > 0 {
+			groups = append(groups, fmt.Sprintf(`date_trunc('%s', %s)`, interval, dateColumn(*dateTrunc)))
+		}
+	}
+
+	fields := make([]string, 0, 10)
+	for index, group := range groups {
+		name := dateTruncName
+		if index < len(groupBy) {
+			name = groupBy[index]
+		}
+		fields = append(fields, fmt.Sprintf(`%s AS %s`, group, quoteIdentifier(name)))
+	}
+	for _, metric := range metrics {
+		column := `*`
+		if len(metric.Field) > 0 {
+			column = quoteColumn(parentAlias, metric.Field)
+		}
+		fields = append(fields, fmt.Sprintf(`%s(%s) AS %s`, strings.ToUpper(string(metric.Function)), column, quoteIdentifier(metric.Name)))
+	}
+
+	from := pq.getFrom()
+	if len(conditions.where) > 0 {
+		from = fmt.Sprintf(`%s WHERE %s`, from, conditions.where)
+	}
+	if pq.hasJoins() {
+		// a joined row per child would count the parent row several times,
+		// the joins only pick the parent rows the metrics run over
+		primaryKey := quoteColumn(parentAlias, pq.PrimaryKey)
+		from = fmt.Sprintf(`FROM %s AS %s WHERE %s IN (SELECT %s %s)`, quoteTable(pq.TableName), parentAlias, primaryKey, primaryKey, from)
+	}
+
+	query := fmt.Sprintf(`SELECT %s %s`, strings.Join(fields, ", "), from)
+
+	if len(groups) > 0 {
+		positions := make([]string, 0, len(groups))
+		for index := range groups {
+			positions = append(positions, fmt.Sprintf(`%d`, index+1))
+		}
+		query = fmt.Sprintf(`%s GROUP BY %s ORDER BY %s`, query, strings.Join(positions, ", "), strings.Join(positions, ", "))
+	}
+
+	return query, conditions.values
+}
+
+// dateTruncName is the row key of the truncated date group.
+const dateTruncName = "period"
+
+func dateColumn(dateTrunc actions.ModuleActionDateTrunc) string {
+	switch dateTrunc.Format {
+	case actions.DateFieldFormatUnix:
+		return fmt.Sprintf(`to_timestamp(%s)`, quoteColumn(parentAlias, dateTrunc.Field))
+	case actions.DateFieldFormatUnixMilli:
+		return fmt.Sprintf(`to_timestamp(%s / 1000.0)`, quoteColumn(parentAlias, dateTrunc.Field))
+	}
+	return quoteColumn(parentAlias, dateTrunc.Field)
 }
@@ -0,0 +1,196 @@
+package db
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/lib/pq"
+	"github.com/portalenergy/pe-request-generator/actions"
+	"github.com/portalenergy/pe-request-generator/fields"
+	log "github.com/sirupsen/logrus"
+)
+
+// splitRelations separates the joins aggregated by the main query
+// from the relations loaded by batched queries.
+func splitRelations(joins []actions.ModuleActionJoin) ([]actions.ModuleActionJoin, []actions.ModuleActionJoin) {
+	sqlJoins := make([]actions.ModuleActionJoin, 0, len(joins))
+	relations := make([]actions.ModuleActionJoin, 0, len(joins))
+	for _, join := range joins {
+		if join.Batch {
+			relations = append(relations, join)
+		} else {
+			sqlJoins = append(sqlJoins, join)
+		}
+	}
+	return sqlJoins, relations
+}
+
+// relationKeyFields adds the parent keys of the relations which are neither the
+// primary key nor selected, so that sparse fields never unlink a relation.
+// The names of the added fields are returned to be stripped from the rows.
+func relationKeyFields(moduleFields []fields.ModuleField, primaryKey string, relations []actions.ModuleActionJoin) ([]fields.ModuleField, []string) {
+	hidden := make([]string, 0, len(relations))
+	for _, relation := range relations {
+		key := relation.OnParentKey
+		if key == primaryKey || containsSelected(moduleFields, key) || containsString(hidden, key) {
+			continue
+		}
+		hidden = append(hidden, key)
+	}
+	if len(hidden) == 0 {
+		return moduleFields, hidden
+	}
+
+	result := make([]fields.ModuleField, 0, len(moduleFields)+len(hidden))
+	result = append(result, moduleFields...)
+	for _, key := range hidden {
+		result = append(result, fields.ModuleField{Name: key})
+	}
+	return result, hidden
+}
+
+func containsSelected(moduleFields []fields.ModuleField, name string) bool {
+	for _, field := range moduleFields {
+		if field.Name == name && field.IsSelected() {
+			return true
+		}
+	}
+	return false
+}
+
+func containsString(names []string, name string) bool {
+	for _, item := range names {
+		if item == name {
+			return true
+		}
+	}
+	return false
+}
+
+// stripFields removes the hidden relation keys from the rows.
+func stripFields(results []interface{}, hidden []string) {
+	if len(hidden) == 0 {
+		return
+	}
+	for _, result := range results {
+		if row, ok := result.(map[string]interface{}); ok {
+			for _, name := range hidden {
+				delete(row, name)
+			}
+		}
+	}
+}
+
+func validateRelation(relation actions.ModuleActionJoin) error {
+	if relation.IsNested() {
+		return invalidIdentifier("batched relation parent", relation.Parent)
+	}
+	if err := validateIdentifiers("table", relation.TableName); err != nil {
+		return err
+	}
+	if err := validateIdentifiers("join alias", relation.ResultArrayName); err != nil {
+		return err
+	}
+	if err := validateIdentifiers("column", relation.OnParentKey, relation.OnKey); err != nil {
+		return err
+	}
+	return validateIdentifiers("column", relation.Fields...)
+}
+
+// relationKey returns the value the relation is linked on, the primary key
+// or a field of the row selected by relationKeyFields.
+func relationKey(relation actions.ModuleActionJoin, primaryKey string, primaryValue interface{}, row map[string]interface{}) interface{} {
+	if relation.OnParentKey == primaryKey {
+		return primaryValue
+	}
+	return row[relation.OnParentKey]
+}
+
+// loadRelations runs one query per batched relation for all rows and attaches the
+// matches to every row, rows are linked by OnParentKey which is the primary key
+// or a field selected by relationKeyFields.
+func (db *DB) loadRelations(
+	log *log.Entry,
+	primaryKey string,
+	results []interface{},
+	primaryValues []interface{},
+	relations []actions.ModuleActionJoin,
+) error {
+	for _, relation := range relations {
+		if err := validateRelation(relation); err != nil {
+			return err
+		}
+
+		keys := make([]interface{}, 0, len(results))
+		for index, result := range results {
+			row, _ := result.(map[string]interface{})
+			if key := relationKey(relation, primaryKey, primaryValues[index], row); key != nil {
+				keys = append(keys, key)
+			}
+		}
+
+		rowsByKey := make(map[string][]map[string]interface{})
+		if len(keys) > 0 {
+			columns := make([]string, 0, len(relation.Fields)+1)
+			columns = append(columns, quoteColumn(relation.ResultArrayName, relation.OnKey))
+			for _, field := range relation.Fields {
+				columns = append(columns, quoteColumn(relation.ResultArrayName, field))
+			}
+
+			query := fmt.Sprintf(
+				`SELECT %s FROM %s AS %s WHERE %s = ANY($1)`,
+				strings.Join(columns, ", "),
+				quoteTable(relation.TableName),
+				relation.ResultArrayName,
+				quoteColumn(relation.ResultArrayName, relation.OnKey),
+			)
+			log.Infoln("RELATION QUERY: ", query)
+
+			rows, err := db.sql.Query(query, pq.Array(keys))
+			if err != nil {
+				log.Errorln("RELATION ERR: ", err)
+				return err
+			}
+
+			for rows.Next() {
+				columnValues := make([]interface{}, 0, len(relation.Fields)+1)
+				for range columns {
+					columnValues = append(columnValues, &fields.NullValue{})
+				}
+				if err = rows.Scan(columnValues...); err != nil {
+					rows.Close()
+					return err
+				}
+
+				relationRow := make(map[string]interface{})
+				for index, field := range relation.Fields {
+					relationRow[field] = columnValues[index+1].(*fields.NullValue).Data
+				}
+				key := fmt.Sprint(columnValues[0].(*fields.NullValue).Data)
+				rowsByKey[key] = append(rowsByKey[key], relationRow)
+			}
+			err = rows.Err()
+			rows.Close()
+			if err != nil {
+				return err
+			}
+		}
+
+		for index, result := range results {
+			row, ok := result.(map[string]interface{})
+			if !ok {
+				continue
+			}
+
+			matches := make([]map[string]interface{}, 0, 10)
+			if key := relationKey(relation, primaryKey, primaryValues[index], row); key != nil {
+				if keyRows, ok := rowsByKey[fmt.Sprint(key)]; ok {
+					matches = keyRows
+				}
+			}
+			row[relation.ResultArrayName] = shapeRows(relation, matches)
+		}
+	}
+
+	return nil
+}
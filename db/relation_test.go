@@ -0,0 +1,220 @@
+package db
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/portalenergy/pe-request-generator/actions"
+	"github.com/portalenergy/pe-request-generator/fields"
+	"github.com/sirupsen/logrus"
+)
+
+// cannedDriver answers every query with the rows of the first answer it
+// contains and records the queries it ran.
+type cannedDriver struct {
+	mu      sync.Mutex
+	queries []string
+	answers []cannedAnswer
+}
+
+type cannedAnswer struct {
+	match   string
+	columns []string
+	rows    [][]driver.Value
+}
+
+func (d *cannedDriver) Open(string) (driver.Conn, error) {
+	return cannedConn{d}, nil
+}
+
+func (d *cannedDriver) answer(query string) (cannedAnswer, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.queries = append(d.queries, query)
+	for _, answer := range d.answers {
+		if strings.Contains(query, answer.match) {
+			return answer, nil
+		}
+	}
+	return cannedAnswer{}, fmt.Errorf("unexpected query %s", query)
+}
+
+type cannedConn struct {
+	driver *cannedDriver
+}
+
+func (conn cannedConn) Prepare(query string) (driver.Stmt, error) {
+	return cannedStmt{conn.driver, query}, nil
+}
+
+func (conn cannedConn) Close() error {
+	return nil
+}
+
+func (conn cannedConn) Begin() (driver.Tx, error) {
+	return nil, fmt.Errorf("transactions are not supported")
+}
+
+type cannedStmt struct {
+	driver *cannedDriver
+	query  string
+}
+
+func (stmt cannedStmt) Close() error {
+	return nil
+}
+
+func (stmt cannedStmt) NumInput() int {
+	return -1
+}
+
+func (stmt cannedStmt) Exec([]driver.Value) (driver.Result, error) {
+	return nil, fmt.Errorf("exec is not supported")
+}
+
+func (stmt cannedStmt) Query([]driver.Value) (driver.Rows, error) {
+	answer, err := stmt.driver.answer(stmt.query)
+	if err != nil {
+		return nil, err
+	}
+	return &cannedRows{answer: answer}, nil
+}
+
+type cannedRows struct {
+	answer cannedAnswer
+	index  int
+}
+
+func (rows *cannedRows) Columns() []string {
+	return rows.answer.columns
+}
+
+func (rows *cannedRows) Close() error {
+	return nil
+}
+
+func (rows *cannedRows) Next(dest []driver.Value) error {
+	if rows.index >= len(rows.answer.rows) {
+		return io.EOF
+	}
+	copy(dest, rows.answer.rows[rows.index])
+	rows.index++
+	return nil
+}
+
+var cannedDrivers sync.Map
+
+func cannedDB(t *testing.T, answers ...cannedAnswer) (*DB, *cannedDriver) {
+	t.Helper()
+	fake := &cannedDriver{answers: answers}
+	name := "canned-" + t.Name()
+	if _, loaded := cannedDrivers.LoadOrStore(name, true); !loaded {
+		sql.Register(name, fake)
+	}
+	conn, err := sql.Open(name, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return NewDB(conn), fake
+}
+
+func TestRelationKeyFields(t *testing.T) {
+	relations := []actions.ModuleActionJoin{
+		{ResultArrayName: "prices", OnParentKey: "id"},
+		{ResultArrayName: "meters", OnParentKey: "company_id"},
+		{ResultArrayName: "owners", OnParentKey: "company_id"},
+		{ResultArrayName: "notes", OnParentKey: "name"},
+	}
+	moduleFields := []fields.ModuleField{{Name: "name"}}
+
+	selected, hidden := relationKeyFields(moduleFields, "id", relations)
+	if len(hidden) != 1 || hidden[0] != "company_id" {
+		t.Fatalf("expected only the unselected parent key to be hidden, got %v", hidden)
+	}
+	if len(selected) != 2 || selected[1].Name != "company_id" || len(moduleFields) != 1 {
+		t.Fatalf("expected the parent key to be appended to a copy of the fields, got %v", selected)
+	}
+
+	results := []interface{}{map[string]interface{}{"name": "north", "company_id": 7}}
+	stripFields(results, hidden)
+	if _, ok := results[0].(map[string]interface{})["company_id"]; ok {
+		t.Fatalf("expected the hidden key to be stripped, got %v", results[0])
+	}
+}
+
+func TestListLoadsRelationsOnUnselectedParentKey(t *testing.T) {
+	conn, fake := cannedDB(t,
+		cannedAnswer{
+			match:   "COUNT(parent.*)",
+			columns: []string{"count"},
+			rows:    [][]driver.Value{{int64(1)}, {int64(1)}, {int64(1)}},
+		},
+		cannedAnswer{
+			match:   "= ANY($1)",
+			columns: []string{"company_id", "serial"},
+			rows: [][]driver.Value{
+				{int64(7), "A-1"},
+				{int64(7), "A-2"},
+				{int64(8), "B-1"},
+			},
+		},
+		cannedAnswer{
+			match:   "SELECT",
+			columns: []string{"id", "name", "company_id"},
+			rows: [][]driver.Value{
+				{int64(1), "north", int64(7)},
+				{int64(2), "south", int64(8)},
+				{int64(3), "east", nil},
+			},
+		},
+	)
+
+	relations := []actions.ModuleActionJoin{{
+		Batch:           true,
+		TableName:       "meters",
+		ResultArrayName: "meters",
+		OnKey:           "company_id",
+		OnParentKey:     "company_id",
+		Fields:          []string{"serial"},
+	}}
+
+	results, count, err := conn.List(logrus.NewEntry(logrus.New()), "stations", "id", []fields.ModuleField{{Name: "name"}}, 0, 10, nil, "", nil, nil, nil, relations, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if count != 3 {
+		t.Fatalf("expected the count of the parent rows, got %d", count)
+	}
+
+	relationQueries := 0
+	for _, query := range fake.queries {
+		if strings.Contains(query, "= ANY($1)") {
+			relationQueries++
+		}
+	}
+	if relationQueries != 1 {
+		t.Fatalf("expected one batched relation query, got %v", fake.queries)
+	}
+
+	expected := []int{2, 1, 0}
+	if len(results) != len(expected) {
+		t.Fatalf("expected %d rows, got %v", len(expected), results)
+	}
+	for index, result := range results {
+		row := result.(map[string]interface{})
+		if _, ok := row["company_id"]; ok {
+			t.Fatalf("expected the parent key to stay out of the output, got %v", row)
+		}
+		meters, ok := row["meters"].([]map[string]interface{})
+		if !ok || len(meters) != expected[index] {
+			t.Fatalf("row %d: expected %d relation rows, got %#v", index, expected[index], row["meters"])
+		}
+	}
+}
@@ -0,0 +1,177 @@
+package db
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/portalenergy/pe-request-generator/actions"
+	"github.com/portalenergy/pe-request-generator/fields"
+	log "github.com/sirupsen/logrus"
+)
+
+// rowMaterializer turns the columns of List and View queries into result rows.
+// The query selects the primary key, the selected fields, one json_agg column
+// per join with fields and the highlight snippets, in this order.
+type rowMaterializer struct {
+	log *log.Entry
+	// moduleFields are all requested fields, Go computed ones included.
+	moduleFields []fields.ModuleField
+	// fields are the fields selected by the query.
+	fields     []fields.ModuleField
+	joins      []actions.ModuleActionJoin
+	highlights []string
+}
+
+func newRowMaterializer(log *log.Entry, moduleFields []fields.ModuleField, joins []actions.ModuleActionJoin, highlights []string) rowMaterializer {
+	selected, _, _, _ := selectFields(moduleFields)
+	return rowMaterializer{
+		log:          log,
+		moduleFields: moduleFields,
+		fields:       selected,
+		joins:        joins,
+		highlights:   highlights,
+	}
+}
+
+// destinations returns the scan destinations of one row.
+func (materializer rowMaterializer) destinations() []interface{} {
+	columnValues := make([]interface{}, 0, 1+len(materializer.fields)+len(materializer.joins)+len(materializer.highlights))
+
+	var primaryValue interface{}
+	columnValues = append(columnValues, &primaryValue)
+
+	for _, field := range materializer.fields {
+		columnValues = append(columnValues, field.Scanner())
+	}
+	for range selectedJoins(materializer.joins) {
+		var columnValue json.RawMessage
+		columnValues = append(columnValues, &columnValue)
+	}
+	for range materializer.highlights {
+		var highlightValue sql.NullString
+		columnValues = append(columnValues, &highlightValue)
+	}
+
+	return columnValues
+}
+
+// materialize builds the result row from scanned destinations.
+func (materializer rowMaterializer) materialize(columnValues []interface{}) map[string]interface{} {
+	result := make(map[string]interface{})
+
+	offset := 1
+	for index, field := range materializer.fields {
+		result[field.Name] = fieldValue(field, columnValues[offset+index])
+	}
+	offset += len(materializer.fields)
+
+	joins := selectedJoins(materializer.joins)
+	rowsByAlias := make(map[string][]map[string]interface{})
+	for index, join := range joins {
+		rawValue, ok := columnValues[offset+index].(*json.RawMessage)
+		if !ok {
+			continue
+		}
+		rowsByAlias[join.ResultArrayName] = materializer.joinRows(joinColumns(join, joins), *rawValue)
+	}
+	for _, join := range joins {
+		if !join.IsNested() {
+			result[join.ResultArrayName] = shapeRows(join, nestRows(join, joins, rowsByAlias, nil))
+		}
+	}
+	offset += len(joins)
+
+	if len(materializer.highlights) > 0 {
+		highlights := make(map[string]string)
+		for index, field := range materializer.highlights {
+			highlightValue, ok := columnValues[offset+index].(*sql.NullString)
+			if ok && highlightValue.Valid {
+				highlights[field] = highlightValue.String
+			}
+		}
+		result[highlightResultName] = highlights
+	}
+
+	computeFields(materializer.moduleFields, result)
+
+	return result
+}
+
+// joinRows decodes a json_agg column of the join columns into unique rows. A join
+// without matches aggregates rows of nulls only and is returned as an empty array.
+func (materializer rowMaterializer) joinRows(columns []string, rawValue json.RawMessage) []map[string]interface{} {
+	joinResults := make([]map[string]interface{}, 0, 10)
+	if len(rawValue) == 0 {
+		return joinResults
+	}
+
+	var joinValues [][]interface{}
+	err := json.Unmarshal(rawValue, &joinValues)
+	if err != nil {
+		materializer.log.Errorln("JOIN ERR: ", err)
+		return joinResults
+	}
+
+	hasValues := false
+	for _, joinValue := range joinValues {
+		for _, value := range joinValue {
+			if value != nil {
+				hasValues = true
+			}
+		}
+	}
+	if !hasValues {
+		return joinResults
+	}
+
+	// rows repeat when several joins multiply each other
+	uniqueKeys := make(map[string]bool)
+	for _, joinValue := range joinValues {
+		resultMap := make(map[string]interface{})
+		for index, field := range columns {
+			if index < len(joinValue) {
+				resultMap[field] = joinValue[index]
+			} else {
+				resultMap[field] = nil
+			}
+		}
+
+		key, err := json.Marshal(resultMap)
+		if err != nil {
+			key = []byte(fmt.Sprint(resultMap))
+		}
+		if uniqueKeys[string(key)] {
+			continue
+		}
+		uniqueKeys[string(key)] = true
+		joinResults = append(joinResults, resultMap)
+	}
+
+	return joinResults
+}
+
+// scanRows materializes every row, rows which fail to scan are logged and skipped.
+// The primary key values of the rows are returned in the same order.
+func (materializer rowMaterializer) scanRows(rows *sql.Rows) ([]interface{}, []interface{}) {
+	results := make([]interface{}, 0, 10)
+	primaryValues := make([]interface{}, 0, 10)
+	for rows.Next() {
+		columnValues := materializer.destinations()
+		err := rows.Scan(columnValues...)
+		if err != nil {
+			materializer.log.Errorln("SCAN ERR: ", err)
+			continue
+		}
+
+		primaryValue := *columnValues[0].(*interface{})
+		// uuid and other non-builtin types come back from the driver as raw bytes
+		if bytesValue, ok := primaryValue.([]byte); ok {
+			primaryValue = string(bytesValue)
+		}
+
+		results = append(results, materializer.materialize(columnValues))
+		primaryValues = append(primaryValues, primaryValue)
+	}
+	return results, primaryValues
+}
@@ -0,0 +1,227 @@
+package db
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/portalenergy/pe-request-generator/actions"
+	"github.com/portalenergy/pe-request-generator/fields"
+	log "github.com/sirupsen/logrus"
+)
+
+// scanRow fills the destinations the way rows.Scan does for the given columns.
+func scanRow(t *testing.T, columnValues []interface{}, columns ...interface{}) {
+	t.Helper()
+	if err := scanColumns(columnValues, columns...); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// scanColumns is scanRow for goroutines, which must not stop the test themselves.
+func scanColumns(columnValues []interface{}, columns ...interface{}) error {
+	if len(columnValues) != len(columns) {
+		return fmt.Errorf("expected %d columns, got %d", len(columnValues), len(columns))
+	}
+
+	for index, column := range columns {
+		switch destination := columnValues[index].(type) {
+		case *interface{}:
+			*destination = column
+		case *json.RawMessage:
+			if column != nil {
+				*destination = json.RawMessage(column.(string))
+			}
+		case sql.Scanner:
+			if err := destination.Scan(column); err != nil {
+				return fmt.Errorf("scan column %d: %v", index, err)
+			}
+		default:
+			return fmt.Errorf("unexpected destination %T", destination)
+		}
+	}
+	return nil
+}
+
+func testFields() []fields.ModuleField {
+	return []fields.ModuleField{
+		{Name: "name", ScanObject: &sql.NullString{}},
+		{Name: "power", ScanObject: &sql.NullInt64{}},
+	}
+}
+
+func TestMaterializeNullColumns(t *testing.T) {
+	moduleFields := testFields()
+	moduleFields[1].ResultValueConverter = func(value interface{}) interface{} {
+		power := value.(*sql.NullInt64)
+		if !power.Valid {
+			return int64(0)
+		}
+		return power.Int64
+	}
+
+	materializer := newRowMaterializer(log.NewEntry(log.New()), moduleFields, nil, nil)
+	columnValues := materializer.destinations()
+	scanRow(t, columnValues, int64(1), nil, nil)
+
+	result := materializer.materialize(columnValues)
+	if result["name"] != nil {
+		t.Fatalf("expected NULL name to be nil, got %#v", result["name"])
+	}
+	if result["power"] != int64(0) {
+		t.Fatalf("expected converted power, got %#v", result["power"])
+	}
+}
+
+func TestMaterializeJoinWithoutMatches(t *testing.T) {
+	joins := []actions.ModuleActionJoin{
+		actions.NewJoin("companies", actions.JoinTypeLeft, "company_id", "id", []string{"id", "name"}, "company"),
+	}
+
+	materializer := newRowMaterializer(log.NewEntry(log.New()), testFields(), joins, nil)
+	columnValues := materializer.destinations()
+	scanRow(t, columnValues, int64(1), "station", int64(50), `[[null,null]]`)
+
+	result := materializer.materialize(columnValues)
+	company, ok := result["company"].([]map[string]interface{})
+	if !ok || len(company) != 0 {
+		t.Fatalf("expected empty join rows, got %#v", result["company"])
+	}
+}
+
+func TestMaterializeMultipleJoins(t *testing.T) {
+	joins := []actions.ModuleActionJoin{
+		actions.NewJoin("companies", actions.JoinTypeLeft, "company_id", "id", []string{"name"}, "company"),
+		actions.NewJoin("filters", actions.JoinTypeLeft, "id", "station_id", nil, "only_filter"),
+		actions.NewJoin("connectors", actions.JoinTypeLeft, "id", "station_id", []string{"id", "type"}, "connectors"),
+	}
+	moduleFields := append(testFields(), fields.ModuleField{
+		Name: "connectors_count",
+		Compute: func(row map[string]interface{}) interface{} {
+			return len(row["connectors"].([]map[string]interface{}))
+		},
+	})
+
+	materializer := newRowMaterializer(log.NewEntry(log.New()), moduleFields, joins, []string{"name"})
+	columnValues := materializer.destinations()
+	scanRow(
+		t,
+		columnValues,
+		int64(1),
+		"station",
+		int64(50),
+		`[["acme"],["acme"]]`,
+		`[[1,"type2"],[2,"ccs"]]`,
+		nil,
+	)
+
+	result := materializer.materialize(columnValues)
+	expectedCompany := []map[string]interface{}{{"name": "acme"}}
+	if !reflect.DeepEqual(result["company"], expectedCompany) {
+		t.Fatalf("expected unique company rows, got %#v", result["company"])
+	}
+
+	expectedConnectors := []map[string]interface{}{
+		{"id": float64(1), "type": "type2"},
+		{"id": float64(2), "type": "ccs"},
+	}
+	if !reflect.DeepEqual(result["connectors"], expectedConnectors) {
+		t.Fatalf("expected connectors of the second join column, got %#v", result["connectors"])
+	}
+	if _, ok := result["only_filter"]; ok {
+		t.Fatalf("join without fields must not be materialized")
+	}
+	if result["connectors_count"] != 2 {
+		t.Fatalf("expected computed field, got %#v", result["connectors_count"])
+	}
+	if _, ok := result[highlightResultName]; !ok {
+		t.Fatalf("expected highlights")
+	}
+}
+
+func TestMaterializeConcurrentRows(t *testing.T) {
+	// one field per Scanner source: the ScanObject prototype, NewScanner and the field type
+	moduleFields := []fields.ModuleField{
+		{Name: "name", ScanObject: &sql.NullString{}},
+		{Name: "power", NewScanner: func() sql.Scanner { return &sql.NullInt64{} }},
+		{Name: "price", Type: fields.ModuleFieldTypeFloat},
+		{Name: "meta"},
+	}
+	materializer := newRowMaterializer(log.NewEntry(log.New()), moduleFields, nil, nil)
+
+	const workers = 16
+	const rows = 200
+	errs := make(chan error, workers)
+	var wait sync.WaitGroup
+	for worker := 0; worker < workers; worker++ {
+		wait.Add(1)
+		go func(worker int) {
+			defer wait.Done()
+			for row := 0; row < rows; row++ {
+				name := fmt.Sprintf("station-%d-%d", worker, row)
+				columnValues := materializer.destinations()
+				err := scanColumns(columnValues, int64(row), name, int64(worker), float64(row), []byte(name))
+				if err != nil {
+					errs <- err
+					return
+				}
+
+				result := materializer.materialize(columnValues)
+				if result["name"] != name || result["power"] != int64(worker) || result["price"] != float64(row) || result["meta"] != name {
+					errs <- fmt.Errorf("row %s got %v", name, result)
+					return
+				}
+			}
+		}(worker)
+	}
+	wait.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Error(err)
+	}
+	if moduleFields[0].ScanObject.(*sql.NullString).Valid {
+		t.Fatal("the ScanObject prototype must never be scanned into")
+	}
+}
+
+func TestMaterializeNestedJoins(t *testing.T) {
+	connector := actions.NewJoin("connectors", actions.JoinTypeLeft, "connector_id", "id", []string{"id", "type"}, "connector")
+	connector.Shape = actions.JoinShapeObject
+	station := actions.NewNestedJoin("connector", "stations", actions.JoinTypeLeft, "station_id", "id", []string{"name"}, "station")
+	station.Shape = actions.JoinShapeObject
+	joins := []actions.ModuleActionJoin{connector, station}
+
+	materializer := newRowMaterializer(log.NewEntry(log.New()), testFields(), joins, nil)
+	columnValues := materializer.destinations()
+	// connector rows carry the hidden station_id, station rows the hidden id
+	scanRow(t, columnValues, int64(1), "session", int64(7), `[[3,"ccs",12]]`, `[["north",12]]`)
+
+	result := materializer.materialize(columnValues)
+	expected := map[string]interface{}{
+		"id":   float64(3),
+		"type": "ccs",
+		"station": map[string]interface{}{
+			"name": "north",
+		},
+	}
+	if !reflect.DeepEqual(result["connector"], expected) {
+		t.Fatalf("expected nested connector object, got %#v", result["connector"])
+	}
+	if _, ok := result["station"]; ok {
+		t.Fatalf("nested join must not be output on the row")
+	}
+
+	query := PostgresQuery{TableName: "sessions", PrimaryKey: "id", Fields: []string{"name"}, Joins: joins, Size: 1}
+	if err := query.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	rendered, _ := query.GetQuery(false)
+	if !strings.Contains(rendered, `LEFT JOIN public."stations" AS station ON connector."station_id"=station."id"`) {
+		t.Fatalf("expected the nested join on the connector alias, got %s", rendered)
+	}
+}
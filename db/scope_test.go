@@ -0,0 +1,77 @@
+package db
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/portalenergy/pe-request-generator/actions"
+)
+
+func TestMismatchedLegacyWhereMatchesNoRow(t *testing.T) {
+	where := &actions.ModuleActionWhere{
+		Fields: []actions.ModuleActionWhereField{{Name: "company_id"}},
+	}
+	if rendered := renderWhere(where, new(int), &[]interface{}{}); rendered != "FALSE" {
+		t.Fatalf("expected a where without values to match no row, got %q", rendered)
+	}
+
+	policy := actions.AndWhere(nil, where)
+	if rendered := renderWhere(policy, new(int), &[]interface{}{}); rendered != "FALSE" {
+		t.Fatalf("expected a broken policy to match no row, got %q", rendered)
+	}
+}
+
+func TestUpdateChecksScopeOnNewValues(t *testing.T) {
+	where := actions.WhereCondition(actions.Eq("company_id", 7))
+	input := map[string]interface{}{"name": "north", "company_id": 8}
+
+	query, values := updateQuery("stations", "id", input, "id", 1, where)
+	expected := `UPDATE public."stations" AS parent SET "company_id" = $1, "name" = $2 WHERE parent."id"=$3 AND parent."company_id" = $4 RETURNING parent."id"`
+	if query != expected {
+		t.Fatalf("unexpected update query:\n%s\n%s", query, expected)
+	}
+	if len(values) != 4 || values[0] != 8 || values[2] != 1 || values[3] != 7 {
+		t.Fatalf("unexpected update values: %v", values)
+	}
+
+	scopeQuery, scopeValues := scopeCountQuery("stations", "id", []interface{}{1}, where)
+	if !strings.HasSuffix(scopeQuery, `WHERE parent."id" = ANY($1) AND parent."company_id" = $2`) || len(scopeValues) != 2 || scopeValues[1] != 7 {
+		t.Fatalf("expected the new values to be checked against the scope, got %s %v", scopeQuery, scopeValues)
+	}
+
+	if scopeQuery, _ := scopeCountQuery("stations", "id", []interface{}{1}, nil); len(scopeQuery) > 0 {
+		t.Fatalf("expected no scope check without where, got %s", scopeQuery)
+	}
+}
+
+func TestRawConditionPlaceholders(t *testing.T) {
+	tests := []struct {
+		name     string
+		raw      string
+		args     []interface{}
+		expected string
+		values   int
+	}{
+		{"args", `parent."power" BETWEEN ? AND ?`, []interface{}{1, 5}, `(parent."power" BETWEEN $2 AND $3)`, 3},
+		{"no args", `parent."deleted_ts" IS NULL`, nil, `(parent."deleted_ts" IS NULL)`, 1},
+		{"jsonb operators", `parent."tags" ?? ? AND parent."tags" ??| ? AND parent."tags" ??& ?`, []interface{}{"a", "b", "c"},
+			`(parent."tags" ? $2 AND parent."tags" ?| $3 AND parent."tags" ?& $4)`, 4},
+		{"missing args", `parent."power" BETWEEN ? AND ?`, []interface{}{1}, "FALSE", 1},
+		{"extra args", `parent."power" > ?`, []interface{}{1, 2}, "FALSE", 1},
+		{"unescaped jsonb operator", `parent."tags" ? 'a'`, nil, "FALSE", 1},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			index := 1
+			values := []interface{}{"scope"}
+			rendered := renderCondition(actions.Raw(test.raw, test.args...), &index, &values)
+			if rendered != test.expected {
+				t.Fatalf("expected %q, got %q", test.expected, rendered)
+			}
+			if len(values) != test.values || index != test.values {
+				t.Fatalf("expected %d bound values, got %v", test.values, values)
+			}
+		})
+	}
+}
@@ -0,0 +1,13 @@
+// Package errs holds the sentinel errors shared by the executor and the
+// response layer, so that neither has to import the other.
+package errs
+
+import "errors"
+
+// ErrNotFound is returned when the requested record does not exist
+// or is excluded by the where clause.
+var ErrNotFound = errors.New("record not found")
+
+// ErrOutOfScope is returned when an update would move the record
+// out of the where clause it was updated under.
+var ErrOutOfScope = errors.New("record would leave the allowed scope")
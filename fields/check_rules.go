@@ -0,0 +1,455 @@
+package fields
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/go-ozzo/ozzo-validation/v4/is"
+)
+
+var phoneRegexp = regexp.MustCompile(`^\+[1-9]\d{1,14}$`)
+
+type CompareOperator string
+
+const (
+	CompareOperatorEq  CompareOperator = "eq"
+	CompareOperatorNe  CompareOperator = "ne"
+	CompareOperatorGt  CompareOperator = "gt"
+	CompareOperatorGte CompareOperator = "gte"
+	CompareOperatorLt  CompareOperator = "lt"
+	CompareOperatorLte CompareOperator = "lte"
+)
+
+func MatchRule(field string, pattern string, scenarios []Scenario) matchRule {
+	return matchRule{
+		Type:      "match",
+		Pattern:   pattern,
+		Field:     field,
+		Scenarios: scenarios,
+		regexp:    regexp.MustCompile(pattern),
+	}
+}
+
+func MinRule(field string, min float64, scenarios []Scenario) numberRule {
+	return numberRule{
+		Type:      "min",
+		Min:       &min,
+		Field:     field,
+		Scenarios: scenarios,
+	}
+}
+
+func MaxRule(field string, max float64, scenarios []Scenario) numberRule {
+	return numberRule{
+		Type:      "max",
+		Max:       &max,
+		Field:     field,
+		Scenarios: scenarios,
+	}
+}
+
+func RangeRule(field string, min float64, max float64, scenarios []Scenario) numberRule {
+	return numberRule{
+		Type:      "range",
+		Min:       &min,
+		Max:       &max,
+		Field:     field,
+		Scenarios: scenarios,
+	}
+}
+
+// DateRangeRule accepts RFC3339 or 2006-01-02 strings and unix seconds,
+// a nil bound is not checked.
+func DateRangeRule(field string, min *time.Time, max *time.Time, scenarios []Scenario) dateRangeRule {
+	return dateRangeRule{
+		Type:      "date_range",
+		Min:       min,
+		Max:       max,
+		Field:     field,
+		Scenarios: scenarios,
+	}
+}
+
+// PhoneRule checks the E.164 format, e.g. +77011234567.
+func PhoneRule(field string, scenarios []Scenario) phoneRule {
+	return phoneRule{
+		Type:      "phone",
+		Field:     field,
+		Scenarios: scenarios,
+	}
+}
+
+func UUIDRule(field string, scenarios []Scenario) uuidRule {
+	return uuidRule{
+		Type:      "uuid",
+		Field:     field,
+		Scenarios: scenarios,
+	}
+}
+
+func IPRule(field string, scenarios []Scenario) ipRule {
+	return ipRule{
+		Type:      "ip",
+		Field:     field,
+		Scenarios: scenarios,
+	}
+}
+
+func LatitudeRule(field string, scenarios []Scenario) numberRule {
+	min, max := float64(-90), float64(90)
+	return numberRule{
+		Type:      "latitude",
+		Min:       &min,
+		Max:       &max,
+		Field:     field,
+		Scenarios: scenarios,
+	}
+}
+
+func LongitudeRule(field string, scenarios []Scenario) numberRule {
+	min, max := float64(-180), float64(180)
+	return numberRule{
+		Type:      "longitude",
+		Min:       &min,
+		Max:       &max,
+		Field:     field,
+		Scenarios: scenarios,
+	}
+}
+
+// UniqueRule fails when exists reports the value is already stored,
+// exists is called on every validation.
+func UniqueRule(field string, exists func(value interface{}) bool, scenarios []Scenario) uniqueRule {
+	return uniqueRule{
+		Type:      "unique",
+		Field:     field,
+		Scenarios: scenarios,
+		exists:    exists,
+	}
+}
+
+// CompareRule compares the field with another submitted field,
+// e.g. CompareRule("end_ts", CompareOperatorGt, "start_ts", scenarios).
+func CompareRule(field string, operator CompareOperator, otherField string, scenarios []Scenario) compareRule {
+	return compareRule{
+		Type:       "compare",
+		Operator:   operator,
+		OtherField: otherField,
+		Field:      field,
+		Scenarios:  scenarios,
+	}
+}
+
+type matchRule struct {
+	CheckRules `json:"-"`
+	Type       string     `json:"type"`
+	Pattern    string     `json:"pattern"`
+	Field      string     `json:"field"`
+	Scenarios  []Scenario `json:"scenarios"`
+	regexp     *regexp.Regexp
+}
+
+type numberRule struct {
+	CheckRules `json:"-"`
+	Type       string     `json:"type"`
+	Min        *float64   `json:"min,omitempty"`
+	Max        *float64   `json:"max,omitempty"`
+	Field      string     `json:"field"`
+	Scenarios  []Scenario `json:"scenarios"`
+}
+
+type dateRangeRule struct {
+	CheckRules `json:"-"`
+	Type       string     `json:"type"`
+	Min        *time.Time `json:"min,omitempty"`
+	Max        *time.Time `json:"max,omitempty"`
+	Field      string     `json:"field"`
+	Scenarios  []Scenario `json:"scenarios"`
+}
+
+type phoneRule struct {
+	CheckRules `json:"-"`
+	Type       string     `json:"type"`
+	Field      string     `json:"field"`
+	Scenarios  []Scenario `json:"scenarios"`
+}
+
+type uuidRule struct {
+	CheckRules `json:"-"`
+	Type       string     `json:"type"`
+	Field      string     `json:"field"`
+	Scenarios  []Scenario `json:"scenarios"`
+}
+
+type ipRule struct {
+	CheckRules `json:"-"`
+	Type       string     `json:"type"`
+	Field      string     `json:"field"`
+	Scenarios  []Scenario `json:"scenarios"`
+}
+
+type uniqueRule struct {
+	CheckRules `json:"-"`
+	Type       string     `json:"type"`
+	Field      string     `json:"field"`
+	Scenarios  []Scenario `json:"scenarios"`
+	exists     func(value interface{}) bool
+}
+
+type compareRule struct {
+	CheckRules `json:"-"`
+	Type       string          `json:"type"`
+	Operator   CompareOperator `json:"operator"`
+	OtherField string          `json:"other_field"`
+	Field      string          `json:"field"`
+	Scenarios  []Scenario      `json:"scenarios"`
+}
+
+func (rule matchRule) GetScenarios() []Scenario {
+	return rule.Scenarios
+}
+
+func (rule numberRule) GetScenarios() []Scenario {
+	return rule.Scenarios
+}
+
+func (rule dateRangeRule) GetScenarios() []Scenario {
+	return rule.Scenarios
+}
+
+func (rule phoneRule) GetScenarios() []Scenario {
+	return rule.Scenarios
+}
+
+func (rule uuidRule) GetScenarios() []Scenario {
+	return rule.Scenarios
+}
+
+func (rule ipRule) GetScenarios() []Scenario {
+	return rule.Scenarios
+}
+
+func (rule uniqueRule) GetScenarios() []Scenario {
+	return rule.Scenarios
+}
+
+func (rule compareRule) GetScenarios() []Scenario {
+	return rule.Scenarios
+}
+
+func (rule matchRule) Validate(obj interface{}) error {
+	if isEmptyValue(obj) {
+		return nil
+	}
+	if err := validation.Match(rule.regexp).Validate(fmt.Sprintf("%v", obj)); err != nil {
+		return NewRuleError(RuleCodeMatch, rule.Field, map[string]interface{}{
+			"pattern": rule.Pattern,
+		})
+	}
+	return nil
+}
+
+func (rule numberRule) Validate(obj interface{}) error {
+	if isEmptyValue(obj) {
+		return nil
+	}
+
+	number, ok := toFloat(obj)
+	if !ok {
+		return NewRuleError(RuleCodeNumber, rule.Field, nil)
+	}
+	if rule.Min != nil && rule.Max != nil && (number < *rule.Min || number > *rule.Max) {
+		return NewRuleError(RuleCodeRange, rule.Field, map[string]interface{}{
+			"min": *rule.Min,
+			"max": *rule.Max,
+		})
+	}
+	if rule.Min != nil && number < *rule.Min {
+		return NewRuleError(RuleCodeMin, rule.Field, map[string]interface{}{
+			"min": *rule.Min,
+		})
+	}
+	if rule.Max != nil && number > *rule.Max {
+		return NewRuleError(RuleCodeMax, rule.Field, map[string]interface{}{
+			"max": *rule.Max,
+		})
+	}
+	return nil
+}
+
+func (rule dateRangeRule) Validate(obj interface{}) error {
+	if isEmptyValue(obj) {
+		return nil
+	}
+
+	date, ok := toTime(obj)
+	if !ok {
+		return NewRuleError(RuleCodeDate, rule.Field, nil)
+	}
+	if (rule.Min != nil && date.Before(*rule.Min)) || (rule.Max != nil && date.After(*rule.Max)) {
+		params := make(map[string]interface{})
+		if rule.Min != nil {
+			params["min"] = rule.Min.Format(time.RFC3339)
+		}
+		if rule.Max != nil {
+			params["max"] = rule.Max.Format(time.RFC3339)
+		}
+		return NewRuleError(RuleCodeDateRange, rule.Field, params)
+	}
+	return nil
+}
+
+func (rule phoneRule) Validate(obj interface{}) error {
+	if isEmptyValue(obj) {
+		return nil
+	}
+	if err := validation.Match(phoneRegexp).Validate(fmt.Sprintf("%v", obj)); err != nil {
+		return NewRuleError(RuleCodePhone, rule.Field, nil)
+	}
+	return nil
+}
+
+func (rule uuidRule) Validate(obj interface{}) error {
+	if err := is.UUID.Validate(obj); err != nil {
+		return NewRuleError(RuleCodeUUID, rule.Field, nil)
+	}
+	return nil
+}
+
+func (rule ipRule) Validate(obj interface{}) error {
+	if err := is.IP.Validate(obj); err != nil {
+		return NewRuleError(RuleCodeIP, rule.Field, nil)
+	}
+	return nil
+}
+
+func (rule uniqueRule) Validate(obj interface{}) error {
+	if isEmptyValue(obj) || rule.exists == nil {
+		return nil
+	}
+	if rule.exists(obj) {
+		return NewRuleError(RuleCodeUnique, rule.Field, nil)
+	}
+	return nil
+}
+
+func (rule compareRule) Validate(obj interface{}) error {
+	return nil
+}
+
+func (rule compareRule) ValidateWith(obj interface{}, data map[string]interface{}) error {
+	other, ok := data[rule.OtherField]
+	if isEmptyValue(obj) || !ok || isEmptyValue(other) {
+		return nil
+	}
+
+	if !compareValues(obj, rule.Operator, other) {
+		return NewRuleError(fmt.Sprintf("%s.%s", RuleCodeCompare, rule.Operator), rule.Field, map[string]interface{}{
+			"other_field": rule.OtherField,
+		})
+	}
+	return nil
+}
+
+// compareValues compares numbers, then dates, then falls back to strings.
+func compareValues(left interface{}, operator CompareOperator, right interface{}) bool {
+	var result int
+
+	leftNumber, leftOk := toFloat(left)
+	rightNumber, rightOk := toFloat(right)
+	if leftOk && rightOk {
+		result = compareFloats(leftNumber, rightNumber)
+	} else if leftDate, ok := toTime(left); ok {
+		rightDate, ok := toTime(right)
+		if !ok {
+			return false
+		}
+		result = compareFloats(float64(leftDate.UnixNano()), float64(rightDate.UnixNano()))
+	} else {
+		leftString, rightString := fmt.Sprintf("%v", left), fmt.Sprintf("%v", right)
+		if leftString < rightString {
+			result = -1
+		} else if leftString > rightString {
+			result = 1
+		}
+	}
+
+	switch operator {
+	case CompareOperatorEq:
+		return result == 0
+	case CompareOperatorNe:
+		return result != 0
+	case CompareOperatorGt:
+		return result > 0
+	case CompareOperatorGte:
+		return result >= 0
+	case CompareOperatorLt:
+		return result < 0
+	case CompareOperatorLte:
+		return result <= 0
+	}
+	return false
+}
+
+func compareFloats(left float64, right float64) int {
+	if left < right {
+		return -1
+	}
+	if left > right {
+		return 1
+	}
+	return 0
+}
+
+func isEmptyValue(obj interface{}) bool {
+	if obj == nil {
+		return true
+	}
+	if value, ok := obj.(string); ok {
+		return len(value) == 0
+	}
+	return false
+}
+
+func toFloat(obj interface{}) (float64, bool) {
+	switch value := obj.(type) {
+	case float64:
+		return value, true
+	case float32:
+		return float64(value), true
+	case int:
+		return float64(value), true
+	case int32:
+		return float64(value), true
+	case int64:
+		return float64(value), true
+	case string:
+		number, err := strconv.ParseFloat(value, 64)
+		return number, err == nil
+	}
+	return 0, false
+}
+
+func toTime(obj interface{}) (time.Time, bool) {
+	switch value := obj.(type) {
+	case time.Time:
+		return value, true
+	case string:
+		for _, layout := range []string{time.RFC3339, "2006-01-02"} {
+			date, err := time.Parse(layout, value)
+			if err == nil {
+				return date, true
+			}
+		}
+		return time.Time{}, false
+	}
+
+	seconds, ok := toFloat(obj)
+	if !ok {
+		return time.Time{}, false
+	}
+	return time.Unix(int64(seconds), 0), true
+}
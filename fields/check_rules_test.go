@@ -0,0 +1,117 @@
+package fields
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCheckRules(t *testing.T) {
+	scenarios := []Scenario{ScenarioAdd}
+	min := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	max := time.Date(2020, 12, 31, 0, 0, 0, 0, time.UTC)
+	stored := map[interface{}]bool{"taken": true}
+	exists := func(value interface{}) bool {
+		return stored[value]
+	}
+
+	tests := []struct {
+		name  string
+		rule  CheckRules
+		value interface{}
+		code  string
+	}{
+		{"match", MatchRule("code", `^[A-Z]{3}$`, scenarios), "KZT", ""},
+		{"match empty", MatchRule("code", `^[A-Z]{3}$`, scenarios), "", ""},
+		{"match fails", MatchRule("code", `^[A-Z]{3}$`, scenarios), "kzt", RuleCodeMatch},
+		{"min", MinRule("power", 10, scenarios), 10, ""},
+		{"min fails", MinRule("power", 10, scenarios), 9.5, RuleCodeMin},
+		{"min not a number", MinRule("power", 10, scenarios), "ten", RuleCodeNumber},
+		{"max string number", MaxRule("power", 10, scenarios), "10", ""},
+		{"max fails", MaxRule("power", 10, scenarios), int64(11), RuleCodeMax},
+		{"range", RangeRule("power", 1, 5, scenarios), 3, ""},
+		{"range fails", RangeRule("power", 1, 5, scenarios), 0, RuleCodeRange},
+		{"date range", DateRangeRule("date", &min, &max, scenarios), "2020-06-01", ""},
+		{"date range rfc3339", DateRangeRule("date", &min, &max, scenarios), "2020-06-01T10:00:00Z", ""},
+		{"date range unix", DateRangeRule("date", &min, &max, scenarios), min.Unix(), ""},
+		{"date range fails", DateRangeRule("date", &min, &max, scenarios), "2021-01-01", RuleCodeDateRange},
+		{"date range open max", DateRangeRule("date", &min, nil, scenarios), "2030-01-01", ""},
+		{"date malformed", DateRangeRule("date", &min, &max, scenarios), "01.06.2020", RuleCodeDate},
+		{"phone", PhoneRule("phone", scenarios), "+77011234567", ""},
+		{"phone fails", PhoneRule("phone", scenarios), "87011234567", RuleCodePhone},
+		{"uuid", UUIDRule("id", scenarios), "b4f1c7b6-8d9e-4a43-9c1f-2f9a3f4f1e10", ""},
+		{"uuid fails", UUIDRule("id", scenarios), "b4f1c7b6", RuleCodeUUID},
+		{"ip", IPRule("ip", scenarios), "10.0.0.1", ""},
+		{"ip v6", IPRule("ip", scenarios), "::1", ""},
+		{"ip fails", IPRule("ip", scenarios), "10.0.0.256", RuleCodeIP},
+		{"latitude", LatitudeRule("lat", scenarios), 43.24, ""},
+		{"latitude fails", LatitudeRule("lat", scenarios), 91, RuleCodeRange},
+		{"longitude", LongitudeRule("lng", scenarios), -180, ""},
+		{"longitude fails", LongitudeRule("lng", scenarios), 180.5, RuleCodeRange},
+		{"unique", UniqueRule("name", exists, scenarios), "free", ""},
+		{"unique fails", UniqueRule("name", exists, scenarios), "taken", RuleCodeUnique},
+		{"unique without lookup", UniqueRule("name", nil, scenarios), "taken", ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assertRuleError(t, test.rule.Validate(test.value), test.code)
+			if len(test.rule.GetScenarios()) != 1 || test.rule.GetScenarios()[0] != ScenarioAdd {
+				t.Fatalf("expected the rule scenarios, got %v", test.rule.GetScenarios())
+			}
+		})
+	}
+}
+
+func TestCompareRule(t *testing.T) {
+	scenarios := []Scenario{ScenarioAdd}
+
+	tests := []struct {
+		name     string
+		operator CompareOperator
+		value    interface{}
+		other    interface{}
+		code     string
+	}{
+		{"gt numbers", CompareOperatorGt, 5, "4", ""},
+		{"gt numbers fails", CompareOperatorGt, 4, 4, RuleCodeCompare + ".gt"},
+		{"gte dates", CompareOperatorGte, "2020-01-02", "2020-01-02", ""},
+		{"lt dates fails", CompareOperatorLt, "2020-01-03", "2020-01-02T00:00:00Z", RuleCodeCompare + ".lt"},
+		{"lte strings", CompareOperatorLte, "abc", "abd", ""},
+		{"eq fails", CompareOperatorEq, "a", "b", RuleCodeCompare + ".eq"},
+		{"ne", CompareOperatorNe, "a", "b", ""},
+		{"date against text fails", CompareOperatorGt, "2020-01-02", "tomorrow", RuleCodeCompare + ".gt"},
+		{"empty value is skipped", CompareOperatorGt, "", 4, ""},
+		{"empty other is skipped", CompareOperatorGt, 4, nil, ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rule := CompareRule("end", test.operator, "start", scenarios)
+			data := map[string]interface{}{"end": test.value, "start": test.other}
+			assertRuleError(t, rule.ValidateWith(test.value, data), test.code)
+
+			if err := rule.Validate(test.value); err != nil {
+				t.Fatalf("compare rule must only validate with the data, got %v", err)
+			}
+		})
+	}
+}
+
+// assertRuleError checks that err is a RuleError with the code, an empty code expects no error.
+func assertRuleError(t *testing.T, err error, code string) {
+	t.Helper()
+	if len(code) == 0 {
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		return
+	}
+
+	ruleError, ok := err.(RuleError)
+	if !ok {
+		t.Fatalf("expected RuleError %s, got %#v", code, err)
+	}
+	if ruleError.Code != code {
+		t.Fatalf("expected code %s, got %s", code, ruleError.Code)
+	}
+}
@@ -0,0 +1,149 @@
+package fields
+
+import (
+	"fmt"
+)
+
+type ConditionOperator string
+
+const (
+	ConditionOperatorEq       ConditionOperator = "eq"
+	ConditionOperatorNe       ConditionOperator = "ne"
+	ConditionOperatorIn       ConditionOperator = "in"
+	ConditionOperatorNotIn    ConditionOperator = "not_in"
+	ConditionOperatorEmpty    ConditionOperator = "empty"
+	ConditionOperatorNotEmpty ConditionOperator = "not_empty"
+)
+
+// FieldCondition is a declarative check of a sibling request value, the admin
+// frontend evaluates the same condition to show, hide or require fields.
+// Value is a list for the in and not_in operators and unused for empty checks.
+type FieldCondition struct {
+	Field    string            `json:"field"`
+	Operator ConditionOperator `json:"operator"`
+	Value    interface{}       `json:"value,omitempty"`
+}
+
+func When(field string, operator ConditionOperator, value interface{}) FieldCondition {
+	return FieldCondition{
+		Field:    field,
+		Operator: operator,
+		Value:    value,
+	}
+}
+
+// Match evaluates the condition against the submitted values,
+// values are compared by their string form as InRule does.
+func (condition FieldCondition) Match(data map[string]interface{}) bool {
+	value := data[condition.Field]
+
+	switch condition.Operator {
+	case ConditionOperatorEmpty:
+		return isEmptyValue(value)
+	case ConditionOperatorNotEmpty:
+		return !isEmptyValue(value)
+	case ConditionOperatorNe:
+		return !sameValues(value, condition.Value)
+	case ConditionOperatorIn, ConditionOperatorNotIn:
+		found := false
+		if values, ok := condition.Value.([]interface{}); ok {
+			for _, item := range values {
+				if sameValues(value, item) {
+					found = true
+					break
+				}
+			}
+		}
+		return found == (condition.Operator == ConditionOperatorIn)
+	}
+	return sameValues(value, condition.Value)
+}
+
+func sameValues(left interface{}, right interface{}) bool {
+	if left == nil || right == nil {
+		return left == nil && right == nil
+	}
+	return fmt.Sprintf("%v", left) == fmt.Sprintf("%v", right)
+}
+
+// RequiredIfRule requires the field when the condition matches,
+// e.g. RequiredIfRule("tariff_price", When("billing_type", ConditionOperatorEq, "paid"), scenarios).
+func RequiredIfRule(field string, condition FieldCondition, scenarios []Scenario) requiredIfRule {
+	return requiredIfRule{
+		Type:      "required_if",
+		When:      condition,
+		Field:     field,
+		Scenarios: scenarios,
+	}
+}
+
+// IfRule applies the rule only when the condition matches.
+func IfRule(condition FieldCondition, rule CheckRules) ifRule {
+	return ifRule{
+		Type: "if",
+		When: condition,
+		Rule: rule,
+	}
+}
+
+type requiredIfRule struct {
+	CheckRules `json:"-"`
+	Type       string         `json:"type"`
+	When       FieldCondition `json:"when"`
+	Field      string         `json:"field"`
+	Scenarios  []Scenario     `json:"scenarios"`
+}
+
+type ifRule struct {
+	CheckRules `json:"-"`
+	Type       string         `json:"type"`
+	When       FieldCondition `json:"when"`
+	Rule       CheckRules     `json:"rule"`
+}
+
+func (rule requiredIfRule) GetScenarios() []Scenario {
+	return rule.Scenarios
+}
+
+func (rule ifRule) GetScenarios() []Scenario {
+	return rule.Rule.GetScenarios()
+}
+
+func (rule requiredIfRule) Validate(obj interface{}) error {
+	return nil
+}
+
+func (rule ifRule) Validate(obj interface{}) error {
+	return nil
+}
+
+func (rule requiredIfRule) ValidateWith(obj interface{}, data map[string]interface{}) error {
+	if !rule.When.Match(data) {
+		return nil
+	}
+	return RequiredRule(rule.Field, rule.Scenarios).Validate(obj)
+}
+
+// ValidateWith applies the wrapped rule without the request, a wrapped
+// ContextCheckRules is only applied by ValidateContext.
+func (rule ifRule) ValidateWith(obj interface{}, data map[string]interface{}) error {
+	if !rule.When.Match(data) {
+		return nil
+	}
+	if crossFieldRule, ok := rule.Rule.(CrossFieldRules); ok {
+		return crossFieldRule.ValidateWith(obj, data)
+	}
+	return rule.Rule.Validate(obj)
+}
+
+// ValidateContext matches the condition on ruleContext.Data and applies the
+// wrapped rule, database rules such as ExistsInTableRule included.
+func (rule ifRule) ValidateContext(ruleContext RuleContext, obj interface{}) error {
+	if !rule.When.Match(ruleContext.Data) {
+		return nil
+	}
+	if contextRule, ok := rule.Rule.(ContextCheckRules); ok {
+		return contextRule.ValidateContext(ruleContext, obj)
+	}
+	return rule.ValidateWith(obj, ruleContext.Data)
+}
@@ -0,0 +1,141 @@
+package fields
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+	"github.com/portalenergy/pe-request-generator/identifier"
+	log "github.com/sirupsen/logrus"
+)
+
+// RuleQuerier is the part of the module executor used by database rules.
+type RuleQuerier interface {
+	RawRequest(log *log.Entry, query string, params ...interface{}) (*sql.Rows, error)
+}
+
+// RuleContext is passed to rules evaluated against the database on every request.
+type RuleContext struct {
+	Context  *gin.Context
+	DB       RuleQuerier
+	Log      *log.Entry
+	Scenario Scenario
+	// Key and Value identify the updated record, both are nil on add.
+	Key   interface{}
+	Value interface{}
+	// Data are the submitted values, conditional rules match their condition on them.
+	Data map[string]interface{}
+}
+
+// ContextCheckRules are rules which need the request and the database,
+// Validate of such rules accepts any value on its own.
+type ContextCheckRules interface {
+	CheckRules
+	ValidateContext(ruleContext RuleContext, obj interface{}) error
+}
+
+// ExistsInTableRule fails when no row of table has column equal to the value.
+func ExistsInTableRule(field string, table string, column string, scenarios []Scenario) tableRule {
+	return tableRule{
+		Type:      "exists",
+		Table:     table,
+		Column:    column,
+		Field:     field,
+		Scenarios: scenarios,
+	}
+}
+
+// UniqueInTableRule fails when a row of table already has column equal to the value,
+// on update the record being updated is excluded.
+func UniqueInTableRule(field string, table string, column string, scenarios []Scenario) tableRule {
+	return tableRule{
+		Type:      "unique_in_table",
+		Table:     table,
+		Column:    column,
+		Field:     field,
+		Scenarios: scenarios,
+	}
+}
+
+// ForeignKeyRule fails when the value does not reference a row of table by key.
+func ForeignKeyRule(field string, table string, key string, scenarios []Scenario) tableRule {
+	return tableRule{
+		Type:      "foreign_key",
+		Table:     table,
+		Column:    key,
+		Field:     field,
+		Scenarios: scenarios,
+	}
+}
+
+type tableRule struct {
+	CheckRules `json:"-"`
+	Type       string     `json:"type"`
+	Table      string     `json:"table"`
+	Column     string     `json:"column"`
+	Field      string     `json:"field"`
+	Scenarios  []Scenario `json:"scenarios"`
+}
+
+func (rule tableRule) GetScenarios() []Scenario {
+	return rule.Scenarios
+}
+
+func (rule tableRule) Validate(obj interface{}) error {
+	return nil
+}
+
+func (rule tableRule) ValidateContext(ruleContext RuleContext, obj interface{}) error {
+	if isEmptyValue(obj) {
+		return nil
+	}
+
+	if err := identifier.Validate("table", rule.Table); err != nil {
+		return err
+	}
+	if err := identifier.Validate("column", rule.Column); err != nil {
+		return err
+	}
+
+	query := fmt.Sprintf(`SELECT exists (SELECT 1 FROM %s WHERE %s=$1`, identifier.QuoteTable(rule.Table), identifier.Quote(rule.Column))
+	params := []interface{}{obj}
+	if rule.Type == "unique_in_table" && ruleContext.Key != nil && ruleContext.Value != nil {
+		key := fmt.Sprint(ruleContext.Key)
+		if err := identifier.Validate("column", key); err != nil {
+			return err
+		}
+		query = fmt.Sprintf(`%s AND %s<>$2`, query, identifier.Quote(key))
+		params = append(params, ruleContext.Value)
+	}
+	query = fmt.Sprintf(`%s)`, query)
+
+	rows, err := ruleContext.DB.RawRequest(ruleContext.Log, query, params...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	var exists bool
+	if rows.Next() {
+		err = rows.Scan(&exists)
+		if err != nil {
+			return err
+		}
+	}
+
+	switch rule.Type {
+	case "unique_in_table":
+		if exists {
+			return NewRuleError(RuleCodeUnique, rule.Field, nil)
+		}
+	case "foreign_key":
+		if !exists {
+			return NewRuleError(RuleCodeForeignKey, rule.Field, nil)
+		}
+	default:
+		if !exists {
+			return NewRuleError(RuleCodeExists, rule.Field, nil)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package fields
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/portalenergy/pe-request-generator/identifier"
+	log "github.com/sirupsen/logrus"
+)
+
+// recordingQuerier records the query and fails it, rules must not get that far
+// with an invalid identifier.
+type recordingQuerier struct {
+	queries []string
+}
+
+func (querier *recordingQuerier) RawRequest(log *log.Entry, query string, params ...interface{}) (*sql.Rows, error) {
+	querier.queries = append(querier.queries, query)
+	return nil, errors.New("no database")
+}
+
+func TestTableRuleIdentifiers(t *testing.T) {
+	tests := []struct {
+		name  string
+		rule  tableRule
+		key   interface{}
+		query string
+	}{
+		{"exists", ExistsInTableRule("company_id", "companies", "id", nil), nil,
+			`SELECT exists (SELECT 1 FROM public."companies" WHERE "id"=$1)`},
+		{"unique on update", UniqueInTableRule("email", "users", "email", nil), "id",
+			`SELECT exists (SELECT 1 FROM public."users" WHERE "email"=$1 AND "id"<>$2)`},
+		{"invalid table", ForeignKeyRule("company_id", `companies" --`, "id", nil), nil, ""},
+		{"invalid column", ExistsInTableRule("company_id", "companies", "id=id OR 1", nil), nil, ""},
+		{"invalid key", UniqueInTableRule("email", "users", "email", nil), `id" OR "1`, ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			querier := &recordingQuerier{}
+			err := test.rule.ValidateContext(RuleContext{
+				DB:    querier,
+				Log:   log.NewEntry(log.New()),
+				Key:   test.key,
+				Value: 1,
+			}, "value")
+
+			if len(test.query) == 0 {
+				if !errors.Is(err, identifier.ErrInvalid) || len(querier.queries) > 0 {
+					t.Fatalf("expected the identifier to be rejected before querying, got %v %v", err, querier.queries)
+				}
+				return
+			}
+			if len(querier.queries) != 1 || querier.queries[0] != test.query {
+				t.Fatalf("expected %s, got %v", test.query, querier.queries)
+			}
+		})
+	}
+}
+
+func TestIfRuleAppliesContextRule(t *testing.T) {
+	rule := IfRule(When("billing_type", ConditionOperatorEq, "paid"), ExistsInTableRule("tariff_id", "tariffs", "id", nil))
+
+	querier := &recordingQuerier{}
+	ruleContext := RuleContext{
+		DB:   querier,
+		Log:  log.NewEntry(log.New()),
+		Data: map[string]interface{}{"billing_type": "free"},
+	}
+	if err := rule.ValidateContext(ruleContext, 7); err != nil || len(querier.queries) > 0 {
+		t.Fatalf("expected the rule to be skipped, got %v %v", err, querier.queries)
+	}
+
+	ruleContext.Data = map[string]interface{}{"billing_type": "paid"}
+	if err := rule.ValidateContext(ruleContext, 7); err == nil || len(querier.queries) != 1 {
+		t.Fatalf("expected the wrapped database rule to run, got %v %v", err, querier.queries)
+	}
+}
@@ -0,0 +1,98 @@
+package fields
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// CheckType validates the value against the field type, object and array
+// fields also check their declared properties and items. Errors carry the
+// value path, e.g. address.city or connectors[1].power.
+func (field ModuleField) CheckType(path string, value interface{}) []RuleError {
+	errs := make([]RuleError, 0, 10)
+	if value == nil {
+		return errs
+	}
+
+	typeError := NewRuleError(RuleCodeType, path, map[string]interface{}{
+		"type": field.Type,
+	})
+
+	switch field.Type {
+	case ModuleFieldTypeString:
+		if _, ok := value.(string); !ok {
+			errs = append(errs, typeError)
+		}
+	case ModuleFieldTypeInt:
+		number, ok := value.(float64)
+		if !ok || number != math.Trunc(number) {
+			errs = append(errs, typeError)
+		}
+	case ModuleFieldTypeFloat:
+		if _, ok := value.(float64); !ok {
+			errs = append(errs, typeError)
+		}
+	case ModuleFieldTypeBool:
+		if _, ok := value.(bool); !ok {
+			errs = append(errs, typeError)
+		}
+	case ModuleFieldTypeArray:
+		items, ok := value.([]interface{})
+		if !ok {
+			errs = append(errs, typeError)
+			break
+		}
+		if field.Items != nil {
+			for index, item := range items {
+				errs = append(errs, field.Items.CheckType(fmt.Sprintf("%s[%d]", path, index), item)...)
+			}
+		}
+	case ModuleFieldTypeObject:
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			errs = append(errs, typeError)
+			break
+		}
+		if len(field.Properties) > 0 {
+			errs = append(errs, CheckProperties(path, field.Properties, object)...)
+		}
+	}
+
+	return errs
+}
+
+// CheckProperties rejects keys of the object which are not declared
+// and checks the type of the declared ones.
+func CheckProperties(path string, properties []ModuleField, object map[string]interface{}) []RuleError {
+	errs := make([]RuleError, 0, 10)
+
+	declared := make(map[string]ModuleField)
+	for _, property := range properties {
+		declared[property.Name] = property
+	}
+
+	keys := make([]string, 0, len(object))
+	for key := range object {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		property, ok := declared[key]
+		if !ok {
+			errs = append(errs, NewRuleError(RuleCodeUnknown, joinPath(path, key), nil))
+			continue
+		}
+		errs = append(errs, property.CheckType(joinPath(path, key), object[key])...)
+	}
+
+	return errs
+}
+
+func joinPath(path string, key string) string {
+	if len(path) == 0 {
+		return key
+	}
+	return fmt.Sprintf("%s.%s", path, key)
+}
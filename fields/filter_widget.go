@@ -0,0 +1,36 @@
+package fields
+
+// FilterWidget is the control of a list filter and the shape of its query value:
+//
+//	exact      filter[name]=value
+//	multi      filter[name]=a,b,c matches any of the values
+//	range      filter[name]=min,max either bound may be empty
+//	date_range filter[name]=2024-01-01,2024-01-31 dates or RFC 3339 times, bounds included
+//	boolean    filter[name]=true
+//
+// A date_range of an int field compares the bounds as unix seconds.
+type FilterWidget string
+
+const (
+	FilterWidgetExact     FilterWidget = "exact"
+	FilterWidgetMulti     FilterWidget = "multi"
+	FilterWidgetRange     FilterWidget = "range"
+	FilterWidgetDateRange FilterWidget = "date_range"
+	FilterWidgetBoolean   FilterWidget = "boolean"
+)
+
+// GetFilterWidget returns the filter widget of the field, by default
+// multiselect fields are multi, checkbox and bool fields are boolean.
+func (field ModuleField) GetFilterWidget() FilterWidget {
+	if len(field.FilterWidget) > 0 {
+		return field.FilterWidget
+	}
+
+	switch {
+	case field.FormType == ModuleFieldFormTypeMultiselect:
+		return FilterWidgetMulti
+	case field.FormType == ModuleFieldFormTypeCheckBox || field.Type == ModuleFieldTypeBool:
+		return FilterWidgetBoolean
+	}
+	return FilterWidgetExact
+}
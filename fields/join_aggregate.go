@@ -0,0 +1,43 @@
+package fields
+
+type AggregateFunction string
+
+const (
+	AggregateFunctionCount AggregateFunction = "count"
+	AggregateFunctionSum   AggregateFunction = "sum"
+	AggregateFunctionAvg   AggregateFunction = "avg"
+	AggregateFunctionMin   AggregateFunction = "min"
+	AggregateFunctionMax   AggregateFunction = "max"
+)
+
+// JoinAggregate computes the field over the rows of TableName whose OnKey
+// equals OnParentKey of the module row, e.g. the number of connectors of a
+// station. Field may be empty for count. Count and sum of no rows are 0.
+type JoinAggregate struct {
+	Function    AggregateFunction `json:"function"`
+	TableName   string            `json:"table_name"`
+	OnParentKey string            `json:"on"`
+	OnKey       string            `json:"on_key"`
+	Field       string            `json:"field,omitempty"`
+}
+
+// NewJoinCount counts the rows of tableName linked by onKey to onParentKey.
+func NewJoinCount(tableName string, onParentKey string, onKey string) *JoinAggregate {
+	return &JoinAggregate{
+		Function:    AggregateFunctionCount,
+		TableName:   tableName,
+		OnParentKey: onParentKey,
+		OnKey:       onKey,
+	}
+}
+
+// NewJoinAggregate applies function to field of the rows of tableName linked by onKey to onParentKey.
+func NewJoinAggregate(function AggregateFunction, tableName string, onParentKey string, onKey string, field string) *JoinAggregate {
+	return &JoinAggregate{
+		Function:    function,
+		TableName:   tableName,
+		OnParentKey: onParentKey,
+		OnKey:       onKey,
+		Field:       field,
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"github.com/gin-gonic/gin"
 	validation "github.com/go-ozzo/ozzo-validation/v4"
 	"github.com/go-ozzo/ozzo-validation/v4/is"
+	"github.com/portalenergy/pe-request-generator/i18n"
 )
 
 const (
@@ -21,6 +22,7 @@ const (
 	ModuleFieldTypeString ModuleFieldType = "string"
 	ModuleFieldTypeInt    ModuleFieldType = "int"
 	ModuleFieldTypeFloat  ModuleFieldType = "float"
+	ModuleFieldTypeBool   ModuleFieldType = "bool"
 	ModuleFieldTypeArray  ModuleFieldType = "array"
 	ModuleFieldTypeObject ModuleFieldType = "object"
 )
@@ -31,6 +33,10 @@ func ModuleFieldTypeOf(value string) (ModuleFieldType, error) {
 		return ModuleFieldTypeString, nil
 	case string(ModuleFieldTypeInt):
 		return ModuleFieldTypeInt, nil
+	case string(ModuleFieldTypeFloat):
+		return ModuleFieldTypeFloat, nil
+	case string(ModuleFieldTypeBool):
+		return ModuleFieldTypeBool, nil
 	case string(ModuleFieldTypeArray):
 		return ModuleFieldTypeArray, nil
 	case string(ModuleFieldTypeObject):
@@ -73,13 +79,42 @@ func ModuleFieldFormTypeOf(value string) (ModuleFieldFormType, error) {
 
 type Scenario string
 
+const (
+	RuleCodeRequired   string = "validation.required"
+	RuleCodeIn         string = "validation.in"
+	RuleCodeEmail      string = "validation.email"
+	RuleCodeUrl        string = "validation.url"
+	RuleCodeLength     string = "validation.length"
+	RuleCodeMatch      string = "validation.match"
+	RuleCodeNumber     string = "validation.number"
+	RuleCodeRange      string = "validation.range"
+	RuleCodeMin        string = "validation.min"
+	RuleCodeMax        string = "validation.max"
+	RuleCodeDate       string = "validation.date"
+	RuleCodeDateRange  string = "validation.date_range"
+	RuleCodePhone      string = "validation.phone"
+	RuleCodeUUID       string = "validation.uuid"
+	RuleCodeIP         string = "validation.ip"
+	RuleCodeUnique     string = "validation.unique"
+	RuleCodeExists     string = "validation.exists"
+	RuleCodeForeignKey string = "validation.foreign_key"
+	RuleCodeCompare    string = "validation.compare"
+	RuleCodeType       string = "validation.type"
+	RuleCodeUnknown    string = "validation.unknown_field"
+	RuleCodeConvert    string = "validation.convert"
+)
+
 const (
 	ScenarioAdd    Scenario = "add"
 	ScenarioUpdate Scenario = "update"
 )
 
 type ModuleField struct {
-	ScanObject           sql.Scanner                                     `json:"-"`
+	// ScanObject is the prototype of the scan destination, rows are scanned
+	// into a fresh value of its type, see Scanner.
+	ScanObject sql.Scanner `json:"-"`
+	// NewScanner creates the scan destination of one row.
+	NewScanner           func() sql.Scanner                              `json:"-"`
 	Name                 string                                          `json:"-"`
 	SelectFunction       *string                                         `json:"-"`
 	Title                string                                          `json:"title"`
@@ -92,6 +127,59 @@ type ModuleField struct {
 	CheckFunc            func(context *gin.Context) []CheckRules         `json:"-"`
 	Convert              func(value interface{}) (interface{}, error)    `json:"-"`
 	ResultValueConverter func(value interface{}) interface{}             `json:"-"`
+	// Expression is selected instead of the column, e.g. `parent.price * parent.kwh`,
+	// filters and sorting use it as well. It must never contain request input.
+	Expression string `json:"-"`
+	// JoinAggregate selects an aggregate over related rows, it is filtered
+	// and sorted like an Expression.
+	JoinAggregate *JoinAggregate `json:"-"`
+	// Compute derives the value in Go from the materialized row,
+	// such fields are not selected, filtered or sorted.
+	Compute    func(row map[string]interface{}) interface{} `json:"-"`
+	Properties []ModuleField                                `json:"properties,omitempty"`
+	Items      *ModuleField                                 `json:"items,omitempty"`
+	VisibleIf  *FieldCondition                              `json:"visible_if,omitempty"`
+	ReadRoles  []string                                     `json:"-"`
+	WriteRoles []string                                     `json:"-"`
+	// FilterWidget overrides the widget of the field as a list filter.
+	FilterWidget FilterWidget `json:"-"`
+}
+
+// IsComputed reports whether the value is not a table column, computed fields are read only.
+func (field ModuleField) IsComputed() bool {
+	return len(field.Expression) > 0 || field.JoinAggregate != nil || field.Compute != nil
+}
+
+// IsSelected reports whether the value is read by the query.
+func (field ModuleField) IsSelected() bool {
+	return field.Compute == nil
+}
+
+// IsVisible reports whether the field is shown for the submitted values,
+// hidden fields are neither validated nor written.
+func (field ModuleField) IsVisible(data map[string]interface{}) bool {
+	return field.VisibleIf == nil || field.VisibleIf.Match(data)
+}
+
+// Translate returns a copy of the field with the title and option labels
+// looked up in the i18n catalogue.
+func (field ModuleField) Translate(locale i18n.Locale) ModuleField {
+	field.Title = i18n.Translate(locale, field.Title, nil)
+	field.Options = TranslateOptions(locale, field.Options)
+	return field
+}
+
+func TranslateOptions(locale i18n.Locale, options []ModuleFieldOptions) []ModuleFieldOptions {
+	if options == nil {
+		return nil
+	}
+
+	translated := make([]ModuleFieldOptions, 0, len(options))
+	for _, option := range options {
+		option.Label = i18n.Translate(locale, option.Label, nil)
+		translated = append(translated, option)
+	}
+	return translated
 }
 
 type ModuleFilterField struct {
@@ -104,6 +192,7 @@ type ModuleFilterField struct {
 	Options    []ModuleFieldOptions                         `json:"options,omitempty"`
 	Check      []CheckRules                                 `json:"-"`
 	Convert    func(value interface{}) (interface{}, error) `json:"-"`
+	Widget     FilterWidget                                 `json:"widget"`
 }
 
 type ModuleFieldOptions struct {
@@ -116,8 +205,16 @@ type CheckRules interface {
 	GetScenarios() []Scenario
 }
 
+// CrossFieldRules are rules which also read the other submitted values,
+// Validate of such rules accepts any value on its own.
+type CrossFieldRules interface {
+	CheckRules
+	ValidateWith(obj interface{}, data map[string]interface{}) error
+}
+
 func RequiredRule(field string, scenarios []Scenario) requiredRule {
 	return requiredRule{
+		Type:      "required",
 		Field:     field,
 		Scenarios: scenarios,
 	}
@@ -125,6 +222,7 @@ func RequiredRule(field string, scenarios []Scenario) requiredRule {
 
 func InRule(field string, values []interface{}, scenarios []Scenario) inRule {
 	return inRule{
+		Type:      "in",
 		Field:     field,
 		Values:    values,
 		Scenarios: scenarios,
@@ -133,6 +231,7 @@ func InRule(field string, values []interface{}, scenarios []Scenario) inRule {
 
 func InDBRule(field string, values func() []interface{}, scenarios []Scenario) inRule {
 	return inRule{
+		Type:      "in",
 		Field:     field,
 		Values:    values(),
 		Scenarios: scenarios,
@@ -141,6 +240,7 @@ func InDBRule(field string, values func() []interface{}, scenarios []Scenario) i
 
 func LenRule(field string, min int, max int, scenarios []Scenario) lengthRule {
 	return lengthRule{
+		Type:      "length",
 		Min:       min,
 		Max:       max,
 		Field:     field,
@@ -150,6 +250,15 @@ func LenRule(field string, min int, max int, scenarios []Scenario) lengthRule {
 
 func UrlRule(field string, scenarios []Scenario) urlRule {
 	return urlRule{
+		Type:      "url",
+		Field:     field,
+		Scenarios: scenarios,
+	}
+}
+
+func EmailRule(field string, scenarios []Scenario) emailRule {
+	return emailRule{
+		Type:      "email",
 		Field:     field,
 		Scenarios: scenarios,
 	}
@@ -157,11 +266,13 @@ func UrlRule(field string, scenarios []Scenario) urlRule {
 
 type requiredRule struct {
 	CheckRules `json:"-"`
+	Type       string     `json:"type"`
 	Field      string     `json:"field"`
 	Scenarios  []Scenario `json:"scenarios"`
 }
 
 type inRule struct {
+	Type      string        `json:"type"`
 	Field     string        `json:"field"`
 	Values    []interface{} `json:"values"`
 	Scenarios []Scenario    `json:"scenarios"`
@@ -211,7 +322,10 @@ func (rule lengthRule) GetScenarios() []Scenario {
 }
 
 func (rule requiredRule) Validate(obj interface{}) error {
-	return validation.Required.Error(fmt.Sprintf("%s - не может быть пустым", rule.Field)).Validate(obj)
+	if err := validation.Required.Validate(obj); err != nil {
+		return NewRuleError(RuleCodeRequired, rule.Field, nil)
+	}
+	return nil
 }
 
 func (rule inRule) Validate(obj interface{}) error {
@@ -222,22 +336,34 @@ func (rule inRule) Validate(obj interface{}) error {
 	for _, validationVal := range rule.Values {
 		stringValues = append(stringValues, fmt.Sprintf("%v", validationVal))
 	}
-	return validation.In(stringValues...).Error(fmt.Sprintf("%s - должен быть одним из %v", rule.Field, rule.Values)).Validate(fmt.Sprintf("%v", obj))
+	if err := validation.In(stringValues...).Validate(fmt.Sprintf("%v", obj)); err != nil {
+		return NewRuleError(RuleCodeIn, rule.Field, map[string]interface{}{
+			"values": rule.Values,
+		})
+	}
+	return nil
 }
 
 func (rule emailRule) Validate(obj interface{}) error {
-	return is.Email.Error(fmt.Sprintf("%s неправильный Email адрес", rule.Field)).Validate(obj)
+	if err := is.Email.Validate(obj); err != nil {
+		return NewRuleError(RuleCodeEmail, rule.Field, nil)
+	}
+	return nil
 }
 
 func (rule urlRule) Validate(obj interface{}) error {
-	return is.URL.Error(fmt.Sprintf("%s неправильный URL адрес", rule.Field)).Validate(obj)
+	if err := is.URL.Validate(obj); err != nil {
+		return NewRuleError(RuleCodeUrl, rule.Field, nil)
+	}
+	return nil
 }
 
 func (rule lengthRule) Validate(obj interface{}) error {
-	return validation.Length(
-		rule.Min,
-		rule.Max,
-	).Error(
-		fmt.Sprintf("%s должен быть в пределах %v - %v", rule.Field, rule.Min, rule.Max),
-	).Validate(obj)
+	if err := validation.Length(rule.Min, rule.Max).Validate(obj); err != nil {
+		return NewRuleError(RuleCodeLength, rule.Field, map[string]interface{}{
+			"min": rule.Min,
+			"max": rule.Max,
+		})
+	}
+	return nil
 }
@@ -0,0 +1,36 @@
+package fields
+
+import (
+	"github.com/portalenergy/pe-request-generator/i18n"
+)
+
+// RuleError is returned by the check rules, Code is the message key
+// in the i18n catalogue and Params fill its placeholders.
+type RuleError struct {
+	Code   string                 `json:"code"`
+	Field  string                 `json:"field"`
+	Params map[string]interface{} `json:"params,omitempty"`
+}
+
+func NewRuleError(code string, field string, params map[string]interface{}) RuleError {
+	return RuleError{
+		Code:   code,
+		Field:  field,
+		Params: params,
+	}
+}
+
+func (err RuleError) Error() string {
+	return err.Translate(i18n.DefaultLocale)
+}
+
+// Translate returns the error message in the given locale.
+func (err RuleError) Translate(locale i18n.Locale) string {
+	params := make(map[string]interface{})
+	for key, value := range err.Params {
+		params[key] = value
+	}
+	params["field"] = err.Field
+
+	return i18n.Translate(locale, err.Code, params)
+}
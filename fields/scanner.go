@@ -0,0 +1,54 @@
+package fields
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"reflect"
+)
+
+// NullValue scans any column as is, bytes are kept as a string.
+type NullValue struct {
+	Data interface{}
+}
+
+func (value *NullValue) Scan(src interface{}) error {
+	if bytesValue, ok := src.([]byte); ok {
+		src = string(bytesValue)
+	}
+	value.Data = src
+	return nil
+}
+
+func (value NullValue) Value() (driver.Value, error) {
+	return value.Data, nil
+}
+
+// Scanner returns a new scan destination for one row. NewScanner is used when set,
+// otherwise a fresh copy of the ScanObject type, otherwise a scanner of the field type.
+// ScanObject itself is never scanned into, it is shared by every request.
+func (field ModuleField) Scanner() sql.Scanner {
+	if field.NewScanner != nil {
+		return field.NewScanner()
+	}
+
+	if field.ScanObject != nil {
+		scanType := reflect.TypeOf(field.ScanObject)
+		if scanType.Kind() == reflect.Ptr {
+			if scanner, ok := reflect.New(scanType.Elem()).Interface().(sql.Scanner); ok {
+				return scanner
+			}
+		}
+	}
+
+	switch field.Type {
+	case ModuleFieldTypeString:
+		return &sql.NullString{}
+	case ModuleFieldTypeInt:
+		return &sql.NullInt64{}
+	case ModuleFieldTypeFloat:
+		return &sql.NullFloat64{}
+	case ModuleFieldTypeBool:
+		return &sql.NullBool{}
+	}
+	return &NullValue{}
+}
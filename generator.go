@@ -1,15 +1,10 @@
 package module
 
 import (
-	"bytes"
-	"encoding/csv"
-	"encoding/json"
 	"fmt"
 	"net/http"
-	"sort"
 
 	"github.com/gin-gonic/gin"
-	validation "github.com/go-ozzo/ozzo-validation/v4"
 	"github.com/portalenergy/pe-request-generator/actions"
 	"github.com/portalenergy/pe-request-generator/db"
 	"github.com/portalenergy/pe-request-generator/fields"
@@ -22,6 +17,7 @@ const (
 	GeneratorErrorAdd    string = "Cannot create record"
 	GeneratorErrorUpdate string = "Cannot update record"
 	GeneratorErrorDelete string = "Cannot delete record"
+	GeneratorErrorView   string = "Cannot view record"
 )
 
 type Generator struct {
@@ -31,6 +27,11 @@ type Generator struct {
 	Features             []Features
 	AuthMiddleware       func(module actions.ModuleAction) gin.HandlerFunc
 	PermissionMiddleware func(action actions.ModuleAction, permissions []string) gin.HandlerFunc
+	// HasRoles reports whether the request user has one of the roles,
+	// it is required by the fields with read or write roles.
+	HasRoles func(c *gin.Context, roles []string) bool
+	// SavedViews enables the saved list views of the users, nil disables them.
+	SavedViews *SavedViews
 }
 
 func NewGenerator(
@@ -64,6 +65,12 @@ func (generator *Generator) Run() {
 	featuresGroup.GET("/features", generator.FeaturesMiddleware())
 
 	for _, module := range generator.Modules {
+		for _, field := range module.Fields {
+			if generator.HasRoles == nil && (len(field.ReadRoles) > 0 || len(field.WriteRoles) > 0) {
+				panic(fmt.Sprintf("roles resolver not implemented in module: %s", module.Name))
+			}
+		}
+
 		featuresModule := Features{
 			ModuleName: module.Label,
 			Actions:    make(map[string]FeaturesActions),
@@ -97,6 +104,42 @@ func (generator *Generator) Run() {
 				}
 
 				listGrpup.GET(module.Name, generator.actionList(module, listAction))
+
+				if generator.SavedViews != nil {
+					featuresModule.Actions["views"] = FeaturesActions{
+						Label: listAction.Label,
+						Url:   fmt.Sprintf("%s/%s/views", module.Path, module.Name),
+						Type:  "GET",
+						Roles: listAction.Permission,
+					}
+					listGrpup.GET(fmt.Sprintf("%s/views", module.Name), generator.actionSavedViewList(module))
+					listGrpup.PUT(fmt.Sprintf("%s/views", module.Name), generator.actionSavedViewAdd(module))
+					listGrpup.POST(fmt.Sprintf("%s/views/:id", module.Name), generator.actionSavedViewUpdate(module))
+					listGrpup.DELETE(fmt.Sprintf("%s/views/:id", module.Name), generator.actionSavedViewDelete(module))
+				}
+			case actions.ModuleActionNameAggregate:
+				aggregateAction, _ := action.(actions.AggregateModuleAction)
+				featuresModule.Actions["aggregate"] = FeaturesActions{
+					Label: aggregateAction.Label,
+					Url:   fmt.Sprintf("%s/%s/aggregate", module.Path, module.Name),
+					Type:  "GET",
+					Roles: aggregateAction.Permission,
+				}
+				aggregateGroup := generator.group.Group(module.Path)
+				if aggregateAction.Auth {
+					if generator.AuthMiddleware == nil {
+						panic(fmt.Sprintf("auth middleware not implemented in module: %s", module.Name))
+					}
+					aggregateGroup.Use(generator.AuthMiddleware(aggregateAction))
+				}
+				if len(aggregateAction.Permission) > 0 {
+					if generator.PermissionMiddleware == nil {
+						panic(fmt.Sprintf("permission middleware not implemented in module: %s", module.Name))
+					}
+					aggregateGroup.Use(generator.PermissionMiddleware(aggregateAction, aggregateAction.Permission))
+				}
+
+				aggregateGroup.GET(fmt.Sprintf("%s/aggregate", module.Name), generator.actionAggregate(module, aggregateAction))
 			case actions.ModuleActionNameAdd:
 				addAction, _ := action.(actions.AddModuleAction)
 				featuresModule.Actions["add"] = FeaturesActions{
@@ -213,25 +256,33 @@ func (generator *Generator) actionList(module *BaseModule, action actions.ListMo
 
 		err := action.BeforeRequest(c)
 		if err != nil {
-			response.ErrorResponse(l, c, http.StatusBadRequest, err.Error(), nil)
+			response.TypedErrorResponse(l, c, err.Error(), response.BadRequestOf(err))
 			return
 		}
 
 		page := int64QueryParam(c, "page", 0)
 		size := int64QueryParam(c, "size", 3000)
 		isCSV := int64QueryParam(c, "csv", 0)
-		filters := generator.normalizeFilters(c.QueryMap("filter"), module, action)
-		searchText := c.Query("search")
-		addFilters := c.Query("addFilters")
-		addHeads := c.Query("addHeads")
-
-		realFields := make([]fields.ModuleField, 0, 10)
-		for _, realField := range module.Fields {
-			if containsStrings(action.Fields, realField.Name) {
-				realFields = append(realFields, realField)
+		params := requestListParams(c)
+		if viewID := c.Query("view"); len(viewID) > 0 {
+			view, err := generator.savedView(c, l, module, viewID)
+			if err != nil {
+				response.TypedErrorResponse(l, c, translate(c, "saved view not found", nil), err)
+				return
 			}
+			params = params.withView(view)
 		}
 
+		realFields := sparseFields(params.Fields, generator.readableFields(c, module, action.Fields))
+		joins := includeJoins(c, action.Join, action.Relations)
+
+		filters := generator.normalizeFilters(params.Filter, module, queryNames(module, generator.readableNames(c, module, action.Filter)), joins)
+		sort := sortParam(params.Sort, queryNames(module, generator.readableNames(c, module, action.Sort)))
+		searchText := params.Search
+		search, fullText := generator.readableSearch(c, module, action.Search, action.FullText)
+		addFilters := c.Query("addFilters")
+		addHeads := c.Query("addHeads")
+
 		var whereResult *actions.ModuleActionWhere
 		if action.Where != nil {
 			whereResult = action.Where(c)
@@ -248,15 +299,18 @@ func (generator *Generator) actionList(module *BaseModule, action actions.ListMo
 			realFields,
 			page,
 			size,
-			action.Search,
+			search,
 			searchText,
+			fullText,
 			filters,
-			whereResult,
-			action.Join,
+			module.Scope(c, whereResult),
+			joins,
+			sort,
+			queryFields(module, filters, sort),
 		)
 
 		if err != nil {
-			response.ErrorResponse(l, c, http.StatusBadRequest, err.Error(), nil)
+			response.TypedErrorResponse(l, c, err.Error(), err)
 			return
 		}
 
@@ -264,10 +318,8 @@ func (generator *Generator) actionList(module *BaseModule, action actions.ListMo
 		if addHeads == "true" {
 			heads = make(map[string]string)
 
-			for _, realField := range module.Fields {
-				if containsStrings(action.Fields, realField.Name) {
-					heads[realField.Name] = realField.Title
-				}
+			for _, realField := range realFields {
+				heads[realField.Name] = translate(c, realField.Title, nil)
 			}
 		}
 
@@ -275,7 +327,7 @@ func (generator *Generator) actionList(module *BaseModule, action actions.ListMo
 		if addFilters == "true" {
 			filter = make(map[string]fields.ModuleFilterField)
 			for _, realField := range module.Fields {
-				if containsStrings(action.Filter, realField.Name) {
+				if containsStrings(action.Filter, realField.Name) && generator.canRead(c, realField) {
 					options := make([]fields.ModuleFieldOptions, 0, 10)
 					if realField.Options != nil {
 						for _, item := range realField.Options {
@@ -291,13 +343,14 @@ func (generator *Generator) actionList(module *BaseModule, action actions.ListMo
 					filterField := fields.ModuleFilterField{
 						ScanObject: realField.ScanObject,
 						Name:       realField.Name,
-						Title:      realField.Title,
+						Title:      translate(c, realField.Title, nil),
 						Type:       realField.Type,
 						FormType:   realField.FormType,
 						Example:    realField.Example,
-						Options:    options,
+						Options:    fields.TranslateOptions(response.RequestLocale(c), options),
 						Check:      realField.Check,
 						Convert:    realField.Convert,
+						Widget:     realField.GetFilterWidget(),
 					}
 					filter[realField.Name] = filterField
 				}
@@ -333,51 +386,134 @@ func (generator *Generator) actionList(module *BaseModule, action actions.ListMo
 		if isCSV == 0 {
 			response.Response(l, c, output)
 		} else {
-			resultJsonString, err := json.Marshal(results)
-			if err != nil {
-				response.ErrorResponse(l, c, http.StatusInternalServerError, err.Error(), nil)
+			generator.responseCSV(l, c, results)
+		}
+	}
+}
+
+func (generator *Generator) actionAggregate(module *BaseModule, action actions.AggregateModuleAction) func(c *gin.Context) {
+	return func(c *gin.Context) {
+		defer action.AfterRequest(c)
+
+		ctx := c.Request.Context()
+		l, _ := icontext.GetLogger(ctx)
+
+		err := action.BeforeRequest(c)
+		if err != nil {
+			response.TypedErrorResponse(l, c, err.Error(), response.BadRequestOf(err))
+			return
+		}
+
+		isCSV := int64QueryParam(c, "csv", 0)
+		filters := generator.normalizeFilters(c.QueryMap("filter"), module, queryNames(module, generator.readableNames(c, module, action.Filter)), action.Join)
+		searchText := c.Query("search")
+		search, fullText := generator.readableSearch(c, module, action.Search, action.FullText)
+
+		groupBy := action.GroupBy
+		if len(c.Query("group_by")) > 0 {
+			groupBy = listQueryParam(c, "group_by", action.GroupBy)
+		}
+
+		var interval actions.DateTruncInterval
+		if len(c.Query("interval")) > 0 {
+			interval = actions.DateTruncInterval(c.Query("interval"))
+			if action.DateTrunc == nil || !action.DateTrunc.AllowInterval(interval) {
+				response.TypedErrorResponse(l, c, translate(c, "interval {interval} not allowed", map[string]interface{}{"interval": interval}), response.NewError(
+					response.ErrorCodeBadRequest,
+					"interval",
+					translate(c, "interval {interval} not allowed", map[string]interface{}{"interval": interval}),
+				))
 				return
 			}
+		}
 
-			var d []map[string]interface{}
-			err = json.Unmarshal(resultJsonString, &d)
-			if err != nil {
-				response.ErrorResponse(l, c, http.StatusInternalServerError, err.Error(), nil)
-				return
+		metrics := action.Metrics
+		if len(c.Query("metrics")) > 0 {
+			metricNames := make([]string, 0, 10)
+			for _, metric := range action.Metrics {
+				metricNames = append(metricNames, metric.Name)
 			}
+			requestedMetrics := listQueryParam(c, "metrics", metricNames)
 
-			csvResults := make([][]string, 0, 10)
-			keys := make([]string, 0, 10)
-			for _, v := range d {
-				for key, _ := range v {
-					keys = append(keys, key)
+			metrics = make([]actions.ModuleActionMetric, 0, 10)
+			for _, metric := range action.Metrics {
+				if containsStrings(requestedMetrics, metric.Name) {
+					metrics = append(metrics, metric)
 				}
-				break
 			}
-			sort.Strings(keys)
-			csvResults = append(csvResults, keys)
-
-			for _, v := range d {
-				values := make([]string, 0, 10)
-				for _, key := range keys {
-					valueString, err := json.Marshal(v[key])
-					if err != nil {
-						continue
-					}
+		}
+		readableNames := generator.readableNames(c, module, append(append([]string{}, groupBy...), metricFields(metrics)...))
+		readableGroupBy := make([]string, 0, len(groupBy))
+		for _, field := range groupBy {
+			if containsStrings(readableNames, field) {
+				readableGroupBy = append(readableGroupBy, field)
+			}
+		}
+		groupBy = readableGroupBy
 
-					values = append(values, string(valueString))
-				}
-				csvResults = append(csvResults, values)
+		readableMetrics := make([]actions.ModuleActionMetric, 0, len(metrics))
+		for _, metric := range metrics {
+			if len(metric.Field) == 0 || containsStrings(readableNames, metric.Field) {
+				readableMetrics = append(readableMetrics, metric)
 			}
+		}
+		metrics = readableMetrics
+
+		if len(metrics) == 0 {
+			response.TypedErrorResponse(l, c, translate(c, "metrics not found", nil), response.NewError(
+				response.ErrorCodeBadRequest,
+				"metrics",
+				translate(c, "metrics not found", nil),
+			))
+			return
+		}
+
+		var whereResult *actions.ModuleActionWhere
+		if action.Where != nil {
+			whereResult = action.Where(c)
+		}
+
+		results, totals, err := generator.db(module).Aggregate(
+			l,
+			module.TableName,
+			module.PrimaryKey,
+			groupBy,
+			action.DateTrunc,
+			interval,
+			metrics,
+			search,
+			searchText,
+			fullText,
+			filters,
+			module.Scope(c, whereResult),
+			action.Join,
+			queryFields(module, filters, nil),
+		)
+		if err != nil {
+			response.TypedErrorResponse(l, c, err.Error(), err)
+			return
+		}
 
-			b := new(bytes.Buffer)
-			w := csv.NewWriter(b)
-			w.Comma = '\t'
-			err = w.WriteAll(csvResults)
-			fmt.Println("ERR: ", err)
+		if len(results) == 0 {
+			results = make([]interface{}, 0, 10)
+		}
 
-			response.ResponseCSV(l, c, b.Bytes())
+		if isCSV != 0 {
+			generator.responseCSV(l, c, results)
+			return
 		}
+
+		output := struct {
+			Extra  interface{}   `json:"extra"`
+			Rows   []interface{} `json:"rows"`
+			Totals interface{}   `json:"totals"`
+		}{
+			Extra:  action.Extra,
+			Rows:   results,
+			Totals: totals,
+		}
+
+		response.Response(l, c, output)
 	}
 }
 
@@ -388,44 +524,52 @@ func (generator *Generator) actionAdd(module *BaseModule, action actions.AddModu
 
 		err := action.BeforeRequest(c)
 		if err != nil {
-			response.ErrorResponse(l, c, http.StatusBadRequest, GeneratorErrorAdd, []string{
-				err.Error(),
-			})
+			response.TypedErrorResponse(l, c, translate(c, GeneratorErrorAdd, nil), response.BadRequestOf(err))
 			return
 		}
 
 		var input map[string]interface{}
 		err = utils.ParseJson(c.Request, &input)
 		if err != nil {
-			response.ErrorResponse(l, c, http.StatusBadRequest, GeneratorErrorAdd, []string{
-				"Parse Input Error",
-			})
+			response.TypedErrorResponse(l, c, translate(c, GeneratorErrorAdd, nil), response.NewError(
+				response.ErrorCodeBadRequest,
+				"",
+				translate(c, "Parse Input Error", nil),
+			))
 			return
 		}
 
-		errs := generator.checkRequest(c, input, module, action, fields.ScenarioAdd)
-		if len(errs) > 0 {
-			response.ErrorResponse(l, c, http.StatusBadRequest, GeneratorErrorAdd, errs)
+		forbiddenErrs := generator.checkWritable(c, input, module, action.Fields)
+		if len(forbiddenErrs) > 0 {
+			response.ErrorsResponse(l, c, http.StatusForbidden, response.ErrorCodeForbidden, translate(c, GeneratorErrorAdd, nil), forbiddenErrs)
 			return
 		}
 
-		realFields := make([]fields.ModuleField, 0, 10)
-		for _, realField := range module.Fields {
-			if containsStrings(action.Fields, realField.Name) {
-				realFields = append(realFields, realField)
-			}
+		errs := generator.checkRequest(c, input, module, action, fields.ScenarioAdd, nil, nil, action.Strict, nil)
+		if len(errs) > 0 {
+			response.ErrorsResponse(l, c, http.StatusBadRequest, response.ErrorCodeValidation, translate(c, GeneratorErrorAdd, nil), errs)
+			return
 		}
 
-		mapInput := generator.mapRequestInput(input, module, action.Fields)
+		realFields := generator.readableFields(c, module, action.Fields)
+
+		mapInput, errs := generator.mapRequestInput(c, input, module, action.Fields, nil)
+		if len(errs) > 0 {
+			response.ErrorsResponse(l, c, http.StatusBadRequest, response.ErrorCodeValidation, translate(c, GeneratorErrorAdd, nil), errs)
+			return
+		}
+		module.Timestamps.ApplyCreate(c, mapInput)
 		fmt.Println(mapInput)
-		output, err := generator.db(module).Add(l, module.TableName, module.PrimaryKey, realFields, mapInput)
+		output, primaryValue, err := generator.db(module).Add(l, module.TableName, module.PrimaryKey, realFields, mapInput)
 		if err != nil {
-			response.ErrorResponse(l, c, http.StatusBadRequest, GeneratorErrorAdd, []string{
-				err.Error(),
-			})
+			response.TypedErrorResponse(l, c, translate(c, GeneratorErrorAdd, nil), err)
 			return
 		}
 
+		if location, ok := generator.viewLocation(module, module.PrimaryKey, primaryValue); ok {
+			c.Header("Location", location)
+		}
+
 		response.Response(l, c, output)
 
 		action.AfterRequest(c)
@@ -439,13 +583,17 @@ func (generator *Generator) actionDefrec(module *BaseModule) func(c *gin.Context
 
 		err := module.Defrec.BeforeRequest(c)
 		if err != nil {
-			response.ErrorResponse(l, c, http.StatusBadRequest, err.Error(), nil)
+			response.TypedErrorResponse(l, c, err.Error(), response.BadRequestOf(err))
 			return
 		}
 
 		output := make([]fields.ModuleField, 0, 10)
 
 		for _, field := range module.Fields {
+			if !generator.canRead(c, field) {
+				continue
+			}
+
 			checkItems := make([]fields.CheckRules, 0, 10)
 			optionItems := make([]fields.ModuleFieldOptions, 0, 10)
 
@@ -473,7 +621,7 @@ func (generator *Generator) actionDefrec(module *BaseModule) func(c *gin.Context
 			field.Options = optionItems
 			field.Check = checkItems
 
-			output = append(output, field)
+			output = append(output, field.Translate(response.RequestLocale(c)))
 		}
 
 		response.Response(l, c, response.NewDefrecResponse(nil, output))
@@ -489,37 +637,21 @@ func (generator *Generator) actionView(module *BaseModule, action actions.ViewMo
 
 		err := action.BeforeRequest(c)
 		if err != nil {
-			response.ErrorResponse(l, c, http.StatusBadRequest, err.Error(), nil)
+			response.TypedErrorResponse(l, c, err.Error(), response.BadRequestOf(err))
 			return
 		}
 
-		whereKey := c.Param("bykey")
-		err = validation.In(action.By...).Error(fmt.Sprintf(`allowed keys %v`, action.By)).Validate(whereKey)
+		whereKey, whereValue, err := generator.recordKey(c, action.By)
 		if err != nil {
-			response.ErrorResponse(l, c, http.StatusBadRequest, GeneratorErrorDelete, []string{
-				err.Error(),
-			})
+			response.TypedErrorResponse(l, c, translate(c, GeneratorErrorView, nil), err)
 			return
 		}
 
-		whereValue := c.Param("value")
-		if len(whereValue) == 0 {
-			response.ErrorResponse(l, c, http.StatusBadRequest, GeneratorErrorDelete, []string{
-				"value param not found",
-			})
-			return
-		}
+		realFields := sparseFields(c.Query("fields"), generator.readableFields(c, module, action.Fields))
 
-		realFields := make([]fields.ModuleField, 0, 10)
-		for _, realField := range module.Fields {
-			if containsStrings(action.Fields, realField.Name) {
-				realFields = append(realFields, realField)
-			}
-		}
-
-		result, err := generator.db(module).View(l, module.TableName, module.PrimaryKey, realFields, []interface{}{whereKey}, []interface{}{whereValue}, &action.Where, action.Join)
+		result, err := generator.db(module).View(l, module.TableName, module.PrimaryKey, realFields, []interface{}{whereKey}, []interface{}{whereValue}, generator.recordScope(c, module, action.Where), includeJoins(c, action.Join, action.Relations))
 		if err != nil {
-			response.ErrorResponse(l, c, http.StatusBadRequest, err.Error(), nil)
+			response.TypedErrorResponse(l, c, err.Error(), err)
 			return
 		}
 
@@ -536,51 +668,56 @@ func (generator *Generator) actionUpdate(module *BaseModule, action actions.Upda
 
 		err := action.BeforeRequest(c)
 		if err != nil {
-			response.ErrorResponse(l, c, http.StatusBadRequest, GeneratorErrorUpdate, nil)
+			response.TypedErrorResponse(l, c, translate(c, GeneratorErrorUpdate, nil), response.BadRequestOf(err))
+			return
+		}
+
+		whereKey, whereValue, err := generator.recordKey(c, action.By)
+		if err != nil {
+			response.TypedErrorResponse(l, c, translate(c, GeneratorErrorUpdate, nil), err)
 			return
 		}
 
-		whereKey := c.Param("bykey")
-		err = validation.In(action.By...).Error(fmt.Sprintf(`allowed keys %v`, action.By)).Validate(whereKey)
+		var input map[string]interface{}
+		err = utils.ParseJson(c.Request, &input)
 		if err != nil {
-			response.ErrorResponse(l, c, http.StatusBadRequest, GeneratorErrorDelete, []string{
-				err.Error(),
-			})
+			response.TypedErrorResponse(l, c, translate(c, GeneratorErrorUpdate, nil), response.NewError(
+				response.ErrorCodeBadRequest,
+				"",
+				translate(c, "Parse Input Error", nil),
+			))
 			return
 		}
 
-		whereValue := c.Param("value")
-		if len(whereValue) == 0 {
-			response.ErrorResponse(l, c, http.StatusBadRequest, GeneratorErrorDelete, []string{
-				"value param not found",
-			})
+		forbiddenErrs := generator.checkWritable(c, input, module, action.Fields)
+		if len(forbiddenErrs) > 0 {
+			response.ErrorsResponse(l, c, http.StatusForbidden, response.ErrorCodeForbidden, translate(c, GeneratorErrorUpdate, nil), forbiddenErrs)
 			return
 		}
 
-		var input map[string]interface{}
-		err = utils.ParseJson(c.Request, &input)
+		stored, err := generator.storedValues(c, l, module, whereKey, whereValue, generator.recordScope(c, module, action.Where))
 		if err != nil {
-			response.ErrorResponse(l, c, http.StatusBadRequest, GeneratorErrorUpdate, nil)
+			response.TypedErrorResponse(l, c, translate(c, GeneratorErrorUpdate, nil), err)
 			return
 		}
 
-		errs := generator.checkRequest(c, input, module, action, fields.ScenarioUpdate)
+		errs := generator.checkRequest(c, input, module, action, fields.ScenarioUpdate, whereKey, whereValue, action.Strict, stored)
 		if len(errs) > 0 {
-			response.ErrorResponse(l, c, http.StatusBadRequest, GeneratorErrorUpdate, errs)
+			response.ErrorsResponse(l, c, http.StatusBadRequest, response.ErrorCodeValidation, translate(c, GeneratorErrorUpdate, nil), errs)
 			return
 		}
 
-		realFields := make([]fields.ModuleField, 0, 10)
-		for _, realField := range module.Fields {
-			if containsStrings(action.Fields, realField.Name) {
-				realFields = append(realFields, realField)
-			}
-		}
+		realFields := generator.readableFields(c, module, action.Fields)
 
-		mapInput := generator.mapRequestInput(input, module, action.Fields)
-		output, err := generator.db(module).Update(l, module.TableName, module.PrimaryKey, realFields, mapInput, whereKey, whereValue)
+		mapInput, errs := generator.mapRequestInput(c, input, module, action.Fields, stored)
+		if len(errs) > 0 {
+			response.ErrorsResponse(l, c, http.StatusBadRequest, response.ErrorCodeValidation, translate(c, GeneratorErrorUpdate, nil), errs)
+			return
+		}
+		module.Timestamps.ApplyUpdate(c, mapInput)
+		output, err := generator.db(module).Update(l, module.TableName, module.PrimaryKey, realFields, mapInput, whereKey, whereValue, generator.recordScope(c, module, action.Where))
 		if err != nil {
-			response.ErrorResponse(l, c, http.StatusBadRequest, GeneratorErrorUpdate, nil)
+			response.TypedErrorResponse(l, c, translate(c, GeneratorErrorUpdate, nil), err)
 			return
 		}
 
@@ -597,32 +734,21 @@ func (generator *Generator) actionDelete(module *BaseModule, action actions.Dele
 
 		err := action.BeforeRequest(c)
 		if err != nil {
-			response.ErrorResponse(l, c, http.StatusBadRequest, GeneratorErrorDelete, nil)
+			response.TypedErrorResponse(l, c, translate(c, GeneratorErrorDelete, nil), response.BadRequestOf(err))
 			return
 		}
 
-		whereKey := c.Param("bykey")
-		err = validation.In(action.By...).Error(fmt.Sprintf(`allowed keys %v`, action.By)).Validate(whereKey)
+		whereKey, whereValue, err := generator.recordKey(c, action.By)
 		if err != nil {
-			response.ErrorResponse(l, c, http.StatusBadRequest, GeneratorErrorDelete, []string{
-				err.Error(),
-			})
-			return
-		}
-
-		whereValue := c.Param("value")
-		if len(whereValue) == 0 {
-			response.ErrorResponse(l, c, http.StatusBadRequest, GeneratorErrorDelete, nil)
+			response.TypedErrorResponse(l, c, translate(c, GeneratorErrorDelete, nil), err)
 			return
 		}
 
-		err = generator.db(module).Delete(l, module.TableName, whereKey, whereValue)
+		err = generator.db(module).Delete(l, module.TableName, whereKey, whereValue, generator.recordScope(c, module, action.Where))
 
 		fmt.Println("DELETE eRROR: ", err)
 		if err != nil {
-			response.ErrorResponse(l, c, http.StatusBadRequest, GeneratorErrorDelete, []string{
-				err.Error(),
-			})
+			response.TypedErrorResponse(l, c, translate(c, GeneratorErrorDelete, nil), err)
 			return
 		}
 
@@ -1,13 +1,27 @@
 package module
 
 import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
 	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	validation "github.com/go-ozzo/ozzo-validation/v4"
 	"github.com/portalenergy/pe-request-generator/actions"
+	"github.com/portalenergy/pe-request-generator/db"
 	"github.com/portalenergy/pe-request-generator/fields"
+	"github.com/portalenergy/pe-request-generator/i18n"
+	"github.com/portalenergy/pe-request-generator/icontext"
+	"github.com/portalenergy/pe-request-generator/response"
+	log "github.com/sirupsen/logrus"
 )
 
 func (generator *Generator) getPagination(page int64, size int64) (int64, int64, int64) {
@@ -25,73 +39,203 @@ func (generator *Generator) getPagination(page int64, size int64) (int64, int64,
 	return limit, offset, page
 }
 
-func (generator *Generator) normalizeFilters(data map[string]string, module *BaseModule, listAction actions.ListModuleAction) map[string]string {
-	resultFilterMap := make(map[string]string)
+// normalizeFilters parses the allowed module filters by their filter widget, values
+// which fail the field rules are dropped. alias.field filters on the fields or the key
+// of one of the action joins match by equality.
+func (generator *Generator) normalizeFilters(data map[string]string, module *BaseModule, allowedFilters []string, joins []actions.ModuleActionJoin) map[string]actions.ModuleActionFilter {
+	resultFilterMap := make(map[string]actions.ModuleActionFilter)
 
-	filters := make(map[string]fields.ModuleField)
 	for _, realField := range module.Fields {
-		if containsStrings(listAction.Filter, realField.Name) {
-			filters[realField.Name] = realField
+		if !containsStrings(allowedFilters, realField.Name) {
+			continue
 		}
-	}
 
-parentLoop:
-	for _, filter := range filters {
-		filterValue, ok := data[filter.Name]
+		filterValue, ok := data[realField.Name]
 		if !ok || len(filterValue) == 0 {
 			continue
 		}
 
-		for _, rule := range filter.Check {
-			if err := rule.Validate(filterValue); err != nil {
-				continue parentLoop
-			}
+		if filter, ok := parseFilter(realField, filterValue); ok {
+			resultFilterMap[realField.Name] = filter
 		}
-
-		resultFilterMap[filter.Name] = filterValue
 	}
 
 	for key, value := range data {
 		result := strings.Split(key, ".")
-		if len(result) > 1 {
-			resultFilterMap[key] = value
+		if len(result) != 2 || !db.IsValidIdentifier(result[1]) || len(value) == 0 {
+			continue
+		}
+
+		for _, join := range joins {
+			if len(join.TableName) > 0 && !join.Batch && join.ResultArrayName == result[0] &&
+				(join.OnKey == result[1] || containsStrings(join.Fields, result[1])) {
+				resultFilterMap[key] = actions.NewFilter(value)
+				break
+			}
 		}
 	}
 
 	return resultFilterMap
 }
 
+// parseFilter reads the query value of the field filter, ok is false
+// when the value is malformed or fails the field rules. Date range bounds
+// of int fields are unix seconds, the ones of other fields are times.
+func parseFilter(field fields.ModuleField, value string) (actions.ModuleActionFilter, bool) {
+	validValue := func(value string) bool {
+		for _, rule := range field.Check {
+			if err := rule.Validate(value); err != nil {
+				return false
+			}
+		}
+		return true
+	}
+
+	switch field.GetFilterWidget() {
+	case fields.FilterWidgetMulti:
+		values := make([]interface{}, 0, 10)
+		used := make([]string, 0, 10)
+		for _, item := range strings.Split(value, ",") {
+			item = strings.TrimSpace(item)
+			if len(item) == 0 || containsStrings(used, item) || !validValue(item) {
+				continue
+			}
+			used = append(used, item)
+			values = append(values, item)
+		}
+		return actions.NewFilter(values...), len(values) > 0
+	case fields.FilterWidgetBoolean:
+		boolValue, err := strconv.ParseBool(value)
+		if err != nil {
+			return actions.ModuleActionFilter{}, false
+		}
+		return actions.NewFilter(boolValue), true
+	case fields.FilterWidgetRange, fields.FilterWidgetDateRange:
+		bounds := strings.SplitN(value, ",", 2)
+		if len(bounds) != 2 {
+			return actions.ModuleActionFilter{}, false
+		}
+
+		filter := actions.ModuleActionFilter{}
+		for index, bound := range bounds {
+			bound = strings.TrimSpace(bound)
+			if len(bound) == 0 {
+				continue
+			}
+
+			var boundValue interface{} = bound
+			if field.GetFilterWidget() == fields.FilterWidgetDateRange {
+				date, ok := parseFilterDate(bound, index == 1)
+				if !ok {
+					return actions.ModuleActionFilter{}, false
+				}
+				boundValue = date
+				if field.Type == fields.ModuleFieldTypeInt {
+					boundValue = date.Unix()
+				}
+			} else if !validValue(bound) {
+				return actions.ModuleActionFilter{}, false
+			}
+
+			if index == 0 {
+				filter.Min = boundValue
+			} else {
+				filter.Max = boundValue
+			}
+		}
+		return filter, filter.Min != nil || filter.Max != nil
+	}
+
+	if !validValue(value) {
+		return actions.ModuleActionFilter{}, false
+	}
+	return actions.NewFilter(value), true
+}
+
+// parseFilterDate reads a date or an RFC 3339 time, a date used
+// as the upper bound includes the whole day.
+func parseFilterDate(value string, isEnd bool) (time.Time, bool) {
+	if date, err := time.Parse(time.RFC3339, value); err == nil {
+		return date, true
+	}
+
+	date, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return time.Time{}, false
+	}
+	if isEnd {
+		date = date.Add(24*time.Hour - time.Second)
+	}
+	return date, true
+}
+
 func (generator *Generator) checkRequest(
 	context *gin.Context,
 	data map[string]interface{},
 	module *BaseModule,
 	action actions.ModuleAction,
 	scenario fields.Scenario,
-) map[string]string {
-	errs := make(map[string]string)
+	key interface{},
+	keyValue interface{},
+	strict bool,
+	stored map[string]interface{},
+) []response.Error {
+	errs := make([]response.Error, 0, 10)
+	values := mergeValues(stored, data)
 	actionFields := action.GetFields()
 	fmt.Println("fields: ", actionFields)
 
+	if strict {
+		declaredFields := make([]fields.ModuleField, 0, 10)
+		for _, field := range module.Fields {
+			if containsStrings(actionFields, field.Name) {
+				declaredFields = append(declaredFields, field)
+			}
+		}
+
+		for _, ruleError := range fields.CheckProperties("", declaredFields, data) {
+			errs = append(errs, typedError(context, ruleError.Field, ruleError))
+		}
+	}
+
+	l, _ := icontext.GetLogger(context.Request.Context())
+	ruleContext := fields.RuleContext{
+		Context:  context,
+		DB:       generator.db(module),
+		Log:      l,
+		Scenario: scenario,
+		Key:      key,
+		Value:    keyValue,
+		Data:     values,
+	}
+
 	for _, fieldName := range actionFields {
 		value := data[fieldName]
 		field := module.GetField(fieldName)
-		if field == nil {
+		if field == nil || !field.IsVisible(values) {
 			continue
 		}
 
 		rules := module.GetRules(context, *field, scenario)
 
 		for _, rule := range rules {
-			err := rule.Validate(value)
+			var err error
+			if contextRule, ok := rule.(fields.ContextCheckRules); ok {
+				err = contextRule.ValidateContext(ruleContext, value)
+			} else if crossFieldRule, ok := rule.(fields.CrossFieldRules); ok {
+				err = crossFieldRule.ValidateWith(value, values)
+			} else {
+				err = rule.Validate(value)
+			}
 			if err != nil {
-				errs[fieldName] = err.Error()
+				errs = append(errs, typedError(context, fieldName, err))
 			}
 		}
 
 		if field.Convert != nil && value != nil {
 			_, err := field.Convert(value)
 			if err != nil {
-				errs[fieldName] = err.Error()
+				errs = append(errs, typedError(context, fieldName, err))
 			}
 		}
 	}
@@ -100,18 +244,23 @@ func (generator *Generator) checkRequest(
 }
 
 func (generator *Generator) mapRequestInput(
+	c *gin.Context,
 	data map[string]interface{},
 	module *BaseModule,
 	actionFields []string,
-) map[string]interface{} {
+	stored map[string]interface{},
+) (map[string]interface{}, []response.Error) {
 	output := make(map[string]interface{})
+	errs := make([]response.Error, 0, 10)
+	values := mergeValues(stored, data)
 
 	for _, field := range module.Fields {
 		value, ok := data[field.Name]
-		if ok && containsStrings(actionFields, field.Name) {
+		if ok && containsStrings(actionFields, field.Name) && field.IsVisible(values) {
 			if field.Convert != nil {
 				convertedValue, err := field.Convert(value)
 				if err != nil {
+					errs = append(errs, typedError(c, field.Name, fields.NewRuleError(fields.RuleCodeConvert, field.Name, nil)))
 					continue
 				}
 				output[field.Name] = convertedValue
@@ -121,7 +270,445 @@ func (generator *Generator) mapRequestInput(
 		}
 	}
 
-	return output
+	return output, errs
+}
+
+// mergeValues returns the stored values of the record overwritten by the submitted ones,
+// conditions of a partial update are matched on the whole record.
+func mergeValues(stored map[string]interface{}, data map[string]interface{}) map[string]interface{} {
+	if stored == nil {
+		return data
+	}
+
+	values := make(map[string]interface{}, len(stored)+len(data))
+	for name, value := range stored {
+		values[name] = value
+	}
+	for name, value := range data {
+		values[name] = value
+	}
+	return values
+}
+
+// storedValues loads the stored columns of the updated record when the module has
+// conditions to match, nil is returned when there are none.
+func (generator *Generator) storedValues(
+	c *gin.Context,
+	l *log.Entry,
+	module *BaseModule,
+	key string,
+	value string,
+	where *actions.ModuleActionWhere,
+) (map[string]interface{}, error) {
+	if !module.hasConditions(c) {
+		return nil, nil
+	}
+
+	storedFields := make([]fields.ModuleField, 0, len(module.Fields))
+	for _, field := range module.Fields {
+		if !field.IsComputed() {
+			storedFields = append(storedFields, field)
+		}
+	}
+
+	result, err := generator.db(module).View(l, module.TableName, module.PrimaryKey, storedFields, []interface{}{key}, []interface{}{value}, where, nil)
+	if err != nil {
+		return nil, err
+	}
+	stored, _ := result.(map[string]interface{})
+	return stored, nil
+}
+
+// responseCSV writes the rows as a tab separated file with a sorted header.
+func (generator *Generator) responseCSV(l *log.Entry, c *gin.Context, results []interface{}) {
+	resultJsonString, err := json.Marshal(results)
+	if err != nil {
+		response.ErrorResponse(l, c, http.StatusInternalServerError, err.Error(), nil)
+		return
+	}
+
+	var d []map[string]interface{}
+	err = json.Unmarshal(resultJsonString, &d)
+	if err != nil {
+		response.ErrorResponse(l, c, http.StatusInternalServerError, err.Error(), nil)
+		return
+	}
+
+	csvResults := make([][]string, 0, 10)
+	keys := make([]string, 0, 10)
+	for _, v := range d {
+		for key, _ := range v {
+			keys = append(keys, key)
+		}
+		break
+	}
+	sort.Strings(keys)
+	csvResults = append(csvResults, keys)
+
+	for _, v := range d {
+		values := make([]string, 0, 10)
+		for _, key := range keys {
+			valueString, err := json.Marshal(v[key])
+			if err != nil {
+				continue
+			}
+
+			values = append(values, string(valueString))
+		}
+		csvResults = append(csvResults, values)
+	}
+
+	b := new(bytes.Buffer)
+	w := csv.NewWriter(b)
+	w.Comma = '\t'
+	err = w.WriteAll(csvResults)
+	if err != nil {
+		l.Errorln("CSV ERR: ", err)
+		response.ErrorResponse(l, c, http.StatusInternalServerError, err.Error(), nil)
+		return
+	}
+
+	response.ResponseCSV(l, c, b.Bytes())
+}
+
+// viewLocation returns the url of the view route for the record with the given key,
+// ok is false when the module has no view action accepting that key.
+func (generator *Generator) viewLocation(module *BaseModule, key string, value interface{}) (string, bool) {
+	for _, action := range module.Actions {
+		viewAction, ok := action.(actions.ViewModuleAction)
+		if !ok {
+			continue
+		}
+
+		for _, by := range viewAction.By {
+			if by == key {
+				return path.Join(
+					generator.group.BasePath(),
+					module.Path,
+					module.Name,
+					"view",
+					key,
+					url.PathEscape(fmt.Sprintf("%v", value)),
+				), true
+			}
+		}
+	}
+
+	return "", false
+}
+
+// fieldRolesKey prefixes the per request cache of field role checks.
+const fieldRolesKey = "fieldRoles:"
+
+// hasRoles reports whether the request user has one of the roles,
+// the answer of HasRoles is cached on the request.
+func (generator *Generator) hasRoles(c *gin.Context, roles []string) bool {
+	cacheKey := fieldRolesKey + strings.Join(roles, ",")
+	if allowed, ok := c.Get(cacheKey); ok {
+		return allowed.(bool)
+	}
+
+	allowed := generator.HasRoles(c, roles)
+	c.Set(cacheKey, allowed)
+	return allowed
+}
+
+// canRead reports whether the request user may see the field.
+func (generator *Generator) canRead(c *gin.Context, field fields.ModuleField) bool {
+	return len(field.ReadRoles) == 0 || generator.hasRoles(c, field.ReadRoles)
+}
+
+// canWrite reports whether the request user may submit the field,
+// computed fields are never written.
+func (generator *Generator) canWrite(c *gin.Context, field fields.ModuleField) bool {
+	if field.IsComputed() {
+		return false
+	}
+	return len(field.WriteRoles) == 0 || generator.hasRoles(c, field.WriteRoles)
+}
+
+// readableFields returns the module fields listed in names which the request user may see.
+func (generator *Generator) readableFields(c *gin.Context, module *BaseModule, names []string) []fields.ModuleField {
+	realFields := make([]fields.ModuleField, 0, 10)
+	for _, realField := range module.Fields {
+		if containsStrings(names, realField.Name) && generator.canRead(c, realField) {
+			realFields = append(realFields, realField)
+		}
+	}
+	return realFields
+}
+
+// readableNames filters names down to the fields the request user may see.
+func (generator *Generator) readableNames(c *gin.Context, module *BaseModule, names []string) []string {
+	result := make([]string, 0, len(names))
+	for _, realField := range generator.readableFields(c, module, names) {
+		result = append(result, realField.Name)
+	}
+	return result
+}
+
+// readableSearch keeps the search fields and the full text highlights the request user may see,
+// joined names such as `station.name` are not module fields and are checked against the joins by the query.
+func (generator *Generator) readableSearch(
+	c *gin.Context,
+	module *BaseModule,
+	search []actions.ModuleActionSearchField,
+	fullText *actions.ModuleActionFullTextSearch,
+) ([]actions.ModuleActionSearchField, *actions.ModuleActionFullTextSearch) {
+	readableSearch := make([]actions.ModuleActionSearchField, 0, len(search))
+	for _, searchField := range search {
+		if generator.canReadName(c, module, searchField.Name) {
+			readableSearch = append(readableSearch, searchField)
+		}
+	}
+
+	if fullText != nil && len(fullText.Highlight) > 0 {
+		readableFullText := *fullText
+		readableFullText.Highlight = make([]string, 0, len(fullText.Highlight))
+		for _, name := range fullText.Highlight {
+			if generator.canReadName(c, module, name) {
+				readableFullText.Highlight = append(readableFullText.Highlight, name)
+			}
+		}
+		fullText = &readableFullText
+	}
+	return readableSearch, fullText
+}
+
+// canReadName reports whether the request user may see the module field of the name,
+// names which are not module fields are kept.
+func (generator *Generator) canReadName(c *gin.Context, module *BaseModule, name string) bool {
+	for _, realField := range module.Fields {
+		if realField.Name == name {
+			return generator.canRead(c, realField)
+		}
+	}
+	return true
+}
+
+// checkWritable returns a forbidden error for every submitted action field
+// the request user may not write.
+func (generator *Generator) checkWritable(c *gin.Context, data map[string]interface{}, module *BaseModule, actionFields []string) []response.Error {
+	errs := make([]response.Error, 0, 10)
+	for _, field := range module.Fields {
+		if _, ok := data[field.Name]; !ok || !containsStrings(actionFields, field.Name) {
+			continue
+		}
+		if !generator.canWrite(c, field) {
+			errs = append(errs, response.NewError(response.ErrorCodeForbidden, field.Name, translate(c, "{field} - field is read only", map[string]interface{}{"field": field.Name})))
+		}
+	}
+	return errs
+}
+
+// contextUser returns the id of the user stored in the request context, nil without one.
+func contextUser(c *gin.Context) interface{} {
+	if user, ok := icontext.GetUser(c.Request.Context()); ok && user != nil {
+		return user.ID
+	}
+	return nil
+}
+
+// translate looks the message up in the catalogue for the request locale.
+func translate(c *gin.Context, key string, params map[string]interface{}) string {
+	return i18n.Translate(response.RequestLocale(c), key, params)
+}
+
+// typedError converts a validation error of the field to the error model,
+// rule errors keep their code and parameters and get the request locale message.
+func typedError(c *gin.Context, fieldName string, err error) response.Error {
+	if ruleError, ok := err.(fields.RuleError); ok {
+		return response.Error{
+			Code:    ruleError.Code,
+			Field:   fieldName,
+			Message: ruleError.Translate(response.RequestLocale(c)),
+			Params:  ruleError.Params,
+		}
+	}
+	return response.NewError(response.ErrorCodeValidation, fieldName, err.Error())
+}
+
+// recordKey reads the bykey and value route params of view, update and delete,
+// bykey must be one of the keys allowed by the action.
+func (generator *Generator) recordKey(c *gin.Context, by []interface{}) (string, string, error) {
+	whereKey := c.Param("bykey")
+	err := validation.In(by...).Validate(whereKey)
+	if err != nil {
+		return "", "", response.Error{
+			Code:    response.ErrorCodeBadRequest,
+			Field:   "bykey",
+			Message: translate(c, "allowed keys {keys}", map[string]interface{}{"keys": by}),
+			Params: map[string]interface{}{
+				"keys": by,
+			},
+		}
+	}
+
+	whereValue := c.Param("value")
+	if len(whereValue) == 0 {
+		return "", "", response.NewError(response.ErrorCodeBadRequest, "value", translate(c, "value param not found", nil))
+	}
+
+	return whereKey, whereValue, nil
+}
+
+// recordScope builds the action where of the request and narrows it by the module policy.
+func (generator *Generator) recordScope(c *gin.Context, module *BaseModule, where func(c *gin.Context) *actions.ModuleActionWhere) *actions.ModuleActionWhere {
+	var whereResult *actions.ModuleActionWhere
+	if where != nil {
+		whereResult = where(c)
+	}
+	return module.Scope(c, whereResult)
+}
+
+// queryNames keeps the names of fields the query can filter or sort by,
+// Go computed fields are left out.
+func queryNames(module *BaseModule, names []string) []string {
+	result := make([]string, 0, len(names))
+	for _, realField := range module.Fields {
+		if containsStrings(names, realField.Name) && realField.IsSelected() {
+			result = append(result, realField.Name)
+		}
+	}
+	return result
+}
+
+// queryFields returns the expression and join aggregate fields of the module the
+// query filters or sorts by, their expressions are built even when not selected.
+func queryFields(module *BaseModule, filters map[string]actions.ModuleActionFilter, sort []actions.ModuleActionSort) []fields.ModuleField {
+	result := make([]fields.ModuleField, 0, 10)
+	for _, realField := range module.Fields {
+		if !realField.IsComputed() || !realField.IsSelected() {
+			continue
+		}
+		_, filtered := filters[realField.Name]
+		if filtered || containsSort(sort, realField.Name) {
+			result = append(result, realField)
+		}
+	}
+	return result
+}
+
+func containsSort(sort []actions.ModuleActionSort, name string) bool {
+	for _, item := range sort {
+		if item.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// sortParam parses the sort param such as `-created_ts,name`,
+// a leading minus sorts descending and only allowed fields are kept.
+func sortParam(param string, allowed []string) []actions.ModuleActionSort {
+	result := make([]actions.ModuleActionSort, 0, 10)
+	used := make([]string, 0, 10)
+	for _, value := range strings.Split(param, ",") {
+		value = strings.TrimSpace(value)
+		name := strings.TrimPrefix(value, "-")
+		if len(name) == 0 || !containsStrings(allowed, name) || containsStrings(used, name) {
+			continue
+		}
+
+		used = append(used, name)
+		result = append(result, actions.ModuleActionSort{
+			Name: name,
+			Desc: strings.HasPrefix(value, "-"),
+		})
+	}
+	return result
+}
+
+// sparseFields narrows the fields to the ones named by the fields param
+// such as `id,name,status`, unknown names are ignored and all fields are kept
+// when the param is missing or names none of them.
+func sparseFields(param string, realFields []fields.ModuleField) []fields.ModuleField {
+	names := make([]string, 0, len(realFields))
+	for _, realField := range realFields {
+		names = append(names, realField.Name)
+	}
+
+	requested := listParam(param, names)
+	if len(requested) == 0 {
+		return realFields
+	}
+
+	result := make([]fields.ModuleField, 0, len(requested))
+	for _, realField := range realFields {
+		if containsStrings(requested, realField.Name) {
+			result = append(result, realField)
+		}
+	}
+	return result
+}
+
+// includeJoins appends the relations named by the include query param to the joins,
+// the parents of an included nested relation are included with it.
+func includeJoins(c *gin.Context, joins []actions.ModuleActionJoin, relations []actions.ModuleActionJoin) []actions.ModuleActionJoin {
+	if len(relations) == 0 {
+		return joins
+	}
+
+	names := make([]string, 0, len(relations))
+	for _, relation := range relations {
+		names = append(names, relation.ResultArrayName)
+	}
+
+	included := listQueryParam(c, "include", names)
+	for index := len(relations) - 1; index >= 0; index-- {
+		relation := relations[index]
+		if containsStrings(included, relation.ResultArrayName) && relation.IsNested() && containsStrings(names, relation.Parent) && !containsStrings(included, relation.Parent) {
+			included = append(included, relation.Parent)
+		}
+	}
+
+	result := make([]actions.ModuleActionJoin, 0, len(joins)+len(included))
+	result = append(result, joins...)
+	for _, relation := range relations {
+		if containsStrings(included, relation.ResultArrayName) {
+			result = append(result, relation)
+		}
+	}
+	return result
+}
+
+func metricFields(metrics []actions.ModuleActionMetric) []string {
+	result := make([]string, 0, len(metrics))
+	for _, metric := range metrics {
+		if len(metric.Field) > 0 {
+			result = append(result, metric.Field)
+		}
+	}
+	return result
+}
+
+// listQueryParam splits a comma separated query param,
+// keeping only the values present in allowed.
+func listQueryParam(c *gin.Context, param string, allowed []string) []string {
+	return listParam(c.Query(param), allowed)
+}
+
+// listParam splits a comma separated value, keeping only the values present in allowed.
+func listParam(param string, allowed []string) []string {
+	result := make([]string, 0, 10)
+	for _, value := range splitParam(param) {
+		if containsStrings(allowed, value) {
+			result = append(result, value)
+		}
+	}
+	return result
+}
+
+// splitParam splits a comma separated value into its unique non empty values.
+func splitParam(param string) []string {
+	result := make([]string, 0, 10)
+	for _, value := range strings.Split(param, ",") {
+		value = strings.TrimSpace(value)
+		if len(value) > 0 && !containsStrings(result, value) {
+			result = append(result, value)
+		}
+	}
+	return result
 }
 
 func queryParam(c *gin.Context, param string) (interface{}, error) {
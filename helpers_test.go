@@ -0,0 +1,238 @@
+package module
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/portalenergy/pe-request-generator/actions"
+	"github.com/portalenergy/pe-request-generator/fields"
+	"github.com/portalenergy/pe-request-generator/response"
+)
+
+func testContext() *gin.Context {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest("GET", "/", nil)
+	return c
+}
+
+func TestMapRequestInputMatchesStoredValues(t *testing.T) {
+	paid := fields.When("billing_type", fields.ConditionOperatorEq, "paid")
+	module := &BaseModule{
+		Fields: []fields.ModuleField{
+			{Name: "billing_type"},
+			{Name: "tariff_price", VisibleIf: &paid},
+		},
+	}
+	actionFields := []string{"billing_type", "tariff_price"}
+	input := map[string]interface{}{"tariff_price": 10}
+
+	generator := &Generator{}
+	output, errs := generator.mapRequestInput(testContext(), input, module, actionFields, map[string]interface{}{"billing_type": "paid"})
+	if len(errs) > 0 || output["tariff_price"] != 10 {
+		t.Fatalf("expected the field visible by the stored value to be written, got %v %v", output, errs)
+	}
+	if _, ok := output["billing_type"]; ok {
+		t.Fatalf("stored values must not be written back, got %v", output)
+	}
+
+	output, _ = generator.mapRequestInput(testContext(), input, module, actionFields, map[string]interface{}{"billing_type": "free"})
+	if _, ok := output["tariff_price"]; ok {
+		t.Fatalf("expected the field hidden by the stored value to be dropped, got %v", output)
+	}
+
+	output, _ = generator.mapRequestInput(testContext(), map[string]interface{}{"billing_type": "paid", "tariff_price": 10}, module, actionFields, map[string]interface{}{"billing_type": "free"})
+	if output["tariff_price"] != 10 {
+		t.Fatalf("expected the submitted value to win over the stored one, got %v", output)
+	}
+}
+
+func TestFieldRolesGoThroughHasRoles(t *testing.T) {
+	calls := 0
+	generator := &Generator{
+		HasRoles: func(c *gin.Context, roles []string) bool {
+			calls++
+			return containsStrings(roles, c.GetHeader("X-Role"))
+		},
+	}
+	module := &BaseModule{
+		Fields: []fields.ModuleField{
+			{Name: "name"},
+			{Name: "tariff", ReadRoles: []string{"billing"}},
+		},
+	}
+	action := actions.ListModuleAction{
+		Search: []actions.ModuleActionSearchField{{Name: "name"}, {Name: "tariff"}},
+	}
+
+	c := testContext()
+	c.Request.Header.Set("X-Role", "operator")
+	search, _ := generator.readableSearch(c, module, action.Search, nil)
+	if len(search) != 1 || search[0].Name != "name" {
+		t.Fatalf("expected the unreadable search field to be left out, got %v", search)
+	}
+	if names := generator.readableNames(c, module, []string{"name", "tariff"}); len(names) != 1 {
+		t.Fatalf("expected only the readable field, got %v", names)
+	}
+	if calls != 1 {
+		t.Fatalf("expected the role check to be cached on the request, got %d calls", calls)
+	}
+
+	c = testContext()
+	c.Request.Header.Set("X-Role", "billing")
+	if names := generator.readableNames(c, module, []string{"name", "tariff"}); len(names) != 2 {
+		t.Fatalf("expected both fields for the billing role, got %v", names)
+	}
+}
+
+func TestReadableSearchKeepsJoinedFields(t *testing.T) {
+	generator := &Generator{
+		HasRoles: func(c *gin.Context, roles []string) bool {
+			return false
+		},
+	}
+	module := &BaseModule{
+		Fields: []fields.ModuleField{
+			{Name: "name"},
+			{Name: "tariff", ReadRoles: []string{"billing"}},
+		},
+	}
+	action := actions.ListModuleAction{
+		Search:   []actions.ModuleActionSearchField{{Name: "name"}, {Name: "station.name"}, {Name: "tariff"}},
+		FullText: &actions.ModuleActionFullTextSearch{Highlight: []string{"name", "station.name", "tariff"}},
+	}
+
+	search, fullText := generator.readableSearch(testContext(), module, action.Search, action.FullText)
+	if len(search) != 2 || search[0].Name != "name" || search[1].Name != "station.name" {
+		t.Fatalf("expected the joined search field to be kept, got %v", search)
+	}
+	if len(fullText.Highlight) != 2 || fullText.Highlight[1] != "station.name" {
+		t.Fatalf("expected the joined highlight to be kept, got %v", fullText.Highlight)
+	}
+	if len(action.FullText.Highlight) != 3 {
+		t.Fatalf("the action full text must not change, got %v", action.FullText.Highlight)
+	}
+}
+
+func TestQueryFieldsOfUnselectedComputedFields(t *testing.T) {
+	module := &BaseModule{
+		Fields: []fields.ModuleField{
+			{Name: "name"},
+			{Name: "label", Expression: `upper(parent."name")`},
+			{Name: "connectors_count", JoinAggregate: fields.NewJoinCount("connectors", "id", "station_id")},
+			{Name: "power", Expression: `parent."kw" * 1000`},
+			{Name: "title", Compute: func(row map[string]interface{}) interface{} { return row["name"] }},
+		},
+	}
+
+	names := queryNames(module, []string{"name", "label", "connectors_count", "title"})
+	if len(names) != 3 || names[0] != "name" || names[1] != "label" || names[2] != "connectors_count" {
+		t.Fatalf("expected the sql fields to be queryable whatever is selected, got %v", names)
+	}
+
+	filters := map[string]actions.ModuleActionFilter{"name": actions.NewFilter("north"), "connectors_count": actions.NewFilter("2")}
+	sort := []actions.ModuleActionSort{{Name: "label"}}
+	queried := queryFields(module, filters, sort)
+	if len(queried) != 2 || queried[0].Name != "label" || queried[1].Name != "connectors_count" {
+		t.Fatalf("expected the filtered and sorted computed fields, got %v", queried)
+	}
+	if queried := queryFields(module, filters, nil); len(queried) != 1 || queried[0].Name != "connectors_count" {
+		t.Fatalf("expected only the filtered computed field, got %v", queried)
+	}
+}
+
+func TestParseFilter(t *testing.T) {
+	scenarios := []fields.Scenario{fields.ScenarioAdd}
+	code := fields.ModuleField{Name: "code", Check: []fields.CheckRules{fields.MatchRule("code", `^[A-Z]+$`, scenarios)}}
+	status := fields.ModuleField{Name: "status", FormType: fields.ModuleFieldFormTypeMultiselect, Check: code.Check}
+	power := fields.ModuleField{Name: "power", FilterWidget: fields.FilterWidgetRange, Check: []fields.CheckRules{fields.MinRule("power", 0, scenarios)}}
+	date := fields.ModuleField{Name: "created", FilterWidget: fields.FilterWidgetDateRange}
+	unix := fields.ModuleField{Name: "created_ts", Type: fields.ModuleFieldTypeInt, FilterWidget: fields.FilterWidgetDateRange}
+	active := fields.ModuleField{Name: "active", Type: fields.ModuleFieldTypeBool}
+
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2024, 1, 31, 23, 59, 59, 0, time.UTC)
+	at := time.Date(2024, 1, 5, 10, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		field  fields.ModuleField
+		value  string
+		filter actions.ModuleActionFilter
+		ok     bool
+	}{
+		{"exact", code, "KZT", actions.NewFilter("KZT"), true},
+		{"exact fails the rules", code, "kzt", actions.ModuleActionFilter{}, false},
+		{"multi", status, "NEW, DONE,,NEW", actions.NewFilter("NEW", "DONE"), true},
+		{"multi drops invalid values", status, "NEW,done", actions.NewFilter("NEW"), true},
+		{"multi without values", status, " , ", actions.ModuleActionFilter{}, false},
+		{"range", power, "1,5", actions.ModuleActionFilter{Min: "1", Max: "5"}, true},
+		{"range open min", power, ",5", actions.ModuleActionFilter{Max: "5"}, true},
+		{"range open max", power, "1,", actions.ModuleActionFilter{Min: "1"}, true},
+		{"range without bounds", power, ",", actions.ModuleActionFilter{}, false},
+		{"range without separator", power, "5", actions.ModuleActionFilter{}, false},
+		{"range fails the rules", power, "-1,5", actions.ModuleActionFilter{}, false},
+		{"range not a number", power, "one,5", actions.ModuleActionFilter{}, false},
+		{"date range", date, "2024-01-01,2024-01-31", actions.ModuleActionFilter{Min: from, Max: to}, true},
+		{"date range rfc3339", date, "2024-01-05T10:30:00Z,", actions.ModuleActionFilter{Min: at}, true},
+		{"date range malformed", date, "01.01.2024,2024-01-31", actions.ModuleActionFilter{}, false},
+		{"date range without separator", date, "2024-01-01", actions.ModuleActionFilter{}, false},
+		{"date range of int field in unix seconds", unix, "2024-01-01,2024-01-31", actions.ModuleActionFilter{Min: from.Unix(), Max: to.Unix()}, true},
+		{"boolean", active, "true", actions.NewFilter(true), true},
+		{"boolean false", active, "0", actions.NewFilter(false), true},
+		{"boolean malformed", active, "yes", actions.ModuleActionFilter{}, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			filter, ok := parseFilter(test.field, test.value)
+			if ok != test.ok {
+				t.Fatalf("expected ok %v, got %v with %#v", test.ok, ok, filter)
+			}
+			if ok && !reflect.DeepEqual(filter, test.filter) {
+				t.Fatalf("expected %#v, got %#v", test.filter, filter)
+			}
+		})
+	}
+}
+
+func TestBeforeRequestErrorsAreBadRequests(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		status int
+		code   string
+	}{
+		{"plain", errors.New("station is locked"), http.StatusBadRequest, response.ErrorCodeBadRequest},
+		{"typed", response.NewError(response.ErrorCodeForbidden, "", "station is locked"), http.StatusForbidden, response.ErrorCodeForbidden},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			action := actions.ListModuleAction{
+				BeforeAction: func(c *gin.Context) error {
+					return test.err
+				},
+			}
+			recorder := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(recorder)
+			c.Request = httptest.NewRequest("GET", "/", nil)
+
+			generator := &Generator{}
+			generator.actionList(&BaseModule{}, action)(c)
+
+			if recorder.Code != test.status {
+				t.Fatalf("expected status %d, got %d", test.status, recorder.Code)
+			}
+			body := recorder.Body.String()
+			if !strings.Contains(body, `"code":"`+test.code+`"`) || !strings.Contains(body, "station is locked") {
+				t.Fatalf("expected the hook error in the response, got %s", body)
+			}
+		})
+	}
+}
@@ -0,0 +1,113 @@
+package i18n
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+type Locale string
+
+const (
+	LocaleRu Locale = "ru"
+	LocaleKk Locale = "kk"
+	LocaleEn Locale = "en"
+)
+
+// DefaultLocale is used when the request does not ask for a known locale.
+var DefaultLocale = LocaleRu
+
+var (
+	catalogueMutex sync.RWMutex
+	catalogue      = map[Locale]map[string]string{
+		LocaleRu: ruMessages,
+		LocaleKk: kkMessages,
+		LocaleEn: enMessages,
+	}
+)
+
+// Register adds translations to the catalogue, messages are keyed by a rule or
+// error code, or by the source text for titles and option labels.
+func Register(locale Locale, messages map[string]string) {
+	catalogueMutex.Lock()
+	defer catalogueMutex.Unlock()
+
+	localeMessages, ok := catalogue[locale]
+	if !ok {
+		localeMessages = make(map[string]string)
+		catalogue[locale] = localeMessages
+	}
+	for key, message := range messages {
+		localeMessages[key] = message
+	}
+}
+
+// IsSupported reports whether the catalogue has messages for the locale.
+func IsSupported(locale Locale) bool {
+	catalogueMutex.RLock()
+	defer catalogueMutex.RUnlock()
+
+	_, ok := catalogue[locale]
+	return ok
+}
+
+// Translate returns the message of key in locale, falling back to the default
+// locale and then to the key itself. {name} placeholders are replaced by params.
+func Translate(locale Locale, key string, params map[string]interface{}) string {
+	catalogueMutex.RLock()
+	message, ok := catalogue[locale][key]
+	if !ok {
+		message, ok = catalogue[DefaultLocale][key]
+	}
+	catalogueMutex.RUnlock()
+
+	if !ok {
+		message = key
+	}
+
+	for name, value := range params {
+		message = strings.Replace(message, fmt.Sprintf("{%s}", name), fmt.Sprintf("%v", value), -1)
+	}
+	return message
+}
+
+// ParseAcceptLanguage returns the supported locale with the highest weight
+// in an Accept-Language header, or DefaultLocale.
+func ParseAcceptLanguage(header string) Locale {
+	type weightedLocale struct {
+		locale Locale
+		weight float64
+	}
+
+	locales := make([]weightedLocale, 0, 10)
+	for _, part := range strings.Split(header, ",") {
+		tag := strings.TrimSpace(part)
+		weight := 1.0
+		if index := strings.Index(tag, ";"); index >= 0 {
+			parameter := strings.TrimSpace(tag[index+1:])
+			tag = strings.TrimSpace(tag[:index])
+			if strings.HasPrefix(parameter, "q=") {
+				parsedWeight, err := strconv.ParseFloat(strings.TrimPrefix(parameter, "q="), 64)
+				if err == nil {
+					weight = parsedWeight
+				}
+			}
+		}
+
+		language := strings.ToLower(strings.SplitN(tag, "-", 2)[0])
+		if len(language) > 0 && IsSupported(Locale(language)) {
+			locales = append(locales, weightedLocale{locale: Locale(language), weight: weight})
+		}
+	}
+
+	if len(locales) == 0 {
+		return DefaultLocale
+	}
+
+	sort.SliceStable(locales, func(i, j int) bool {
+		return locales[i].weight > locales[j].weight
+	})
+	return locales[0].locale
+}
@@ -0,0 +1,160 @@
+package i18n
+
+var ruMessages = map[string]string{
+	"validation.required":                  "{field} - не может быть пустым",
+	"validation.in":                        "{field} - должен быть одним из {values}",
+	"validation.email":                     "{field} неправильный Email адрес",
+	"validation.url":                       "{field} неправильный URL адрес",
+	"validation.length":                    "{field} должен быть в пределах {min} - {max}",
+	"validation.match":                     "{field} не соответствует формату",
+	"validation.number":                    "{field} должен быть числом",
+	"validation.range":                     "{field} должен быть в пределах {min} - {max}",
+	"validation.min":                       "{field} должен быть не меньше {min}",
+	"validation.max":                       "{field} должен быть не больше {max}",
+	"validation.date":                      "{field} неправильная дата",
+	"validation.date_range":                "{field} дата вне допустимого диапазона",
+	"validation.phone":                     "{field} неправильный номер телефона",
+	"validation.uuid":                      "{field} неправильный UUID",
+	"validation.ip":                        "{field} неправильный IP адрес",
+	"validation.unique":                    "{field} - такое значение уже существует",
+	"validation.exists":                    "{field} - запись не найдена",
+	"validation.foreign_key":               "{field} - связанная запись не найдена",
+	"validation.compare.eq":                "{field} должен быть равен {other_field}",
+	"validation.compare.ne":                "{field} должен быть не равен {other_field}",
+	"validation.compare.gt":                "{field} должен быть больше {other_field}",
+	"validation.compare.gte":               "{field} должен быть не меньше {other_field}",
+	"validation.compare.lt":                "{field} должен быть меньше {other_field}",
+	"validation.compare.lte":               "{field} должен быть не больше {other_field}",
+	"validation.type":                      "{field} должен иметь тип {type}",
+	"validation.unknown_field":             "{field} - неизвестное поле",
+	"validation.convert":                   "{field} - неправильное значение",
+	"Cannot create record":                 "Не удалось создать запись",
+	"Cannot update record":                 "Не удалось обновить запись",
+	"Cannot delete record":                 "Не удалось удалить запись",
+	"Cannot view record":                   "Не удалось получить запись",
+	"value param not found":                "Параметр value не найден",
+	"Parse Input Error":                    "Ошибка разбора запроса",
+	"allowed keys {keys}":                  "Допустимые ключи {keys}",
+	"interval {interval} not allowed":      "Интервал {interval} не разрешен",
+	"metrics not found":                    "Метрики не найдены",
+	"{field} - field is read only":         "{field} - нет прав на изменение поля",
+	"Cannot save view":                     "Не удалось сохранить вид",
+	"saved view not found":                 "Сохраненный вид не найден",
+	"role {role} not allowed":              "Роль {role} не разрешена",
+	"record not found":                     "Запись не найдена",
+	"internal error":                       "Внутренняя ошибка сервера",
+	"db.error":                             "Ошибка базы данных",
+	"db.unique_violation":                  "Такая запись уже существует",
+	"db.foreign_key_violation":             "Нарушена связь с другой записью",
+	"db.not_null_violation":                "Обязательное значение не заполнено",
+	"db.check_violation":                   "Значение не прошло проверку",
+	"db.invalid_input":                     "Неправильное значение",
+	"record would leave the allowed scope": "Запись выйдет за пределы доступной области",
+	"user is not authorized":               "Пользователь не авторизован",
+}
+
+var kkMessages = map[string]string{
+	"validation.required":                  "{field} - бос болмауы керек",
+	"validation.in":                        "{field} - мына мәндердің бірі болуы керек: {values}",
+	"validation.email":                     "{field} қате Email мекенжайы",
+	"validation.url":                       "{field} қате URL мекенжайы",
+	"validation.length":                    "{field} ұзындығы {min} - {max} аралығында болуы керек",
+	"validation.match":                     "{field} форматқа сәйкес келмейді",
+	"validation.number":                    "{field} сан болуы керек",
+	"validation.range":                     "{field} {min} - {max} аралығында болуы керек",
+	"validation.min":                       "{field} {min} мәнінен кем болмауы керек",
+	"validation.max":                       "{field} {max} мәнінен аспауы керек",
+	"validation.date":                      "{field} қате күн",
+	"validation.date_range":                "{field} күні рұқсат етілген аралықтан тыс",
+	"validation.phone":                     "{field} қате телефон нөмірі",
+	"validation.uuid":                      "{field} қате UUID",
+	"validation.ip":                        "{field} қате IP мекенжайы",
+	"validation.unique":                    "{field} - мұндай мән бұрыннан бар",
+	"validation.exists":                    "{field} - жазба табылмады",
+	"validation.foreign_key":               "{field} - байланысты жазба табылмады",
+	"validation.compare.eq":                "{field} {other_field} мәніне тең болуы керек",
+	"validation.compare.ne":                "{field} {other_field} мәніне тең болмауы керек",
+	"validation.compare.gt":                "{field} {other_field} мәнінен үлкен болуы керек",
+	"validation.compare.gte":               "{field} {other_field} мәнінен кем болмауы керек",
+	"validation.compare.lt":                "{field} {other_field} мәнінен кіші болуы керек",
+	"validation.compare.lte":               "{field} {other_field} мәнінен аспауы керек",
+	"validation.type":                      "{field} {type} түрінде болуы керек",
+	"validation.unknown_field":             "{field} - белгісіз өріс",
+	"validation.convert":                   "{field} - қате мән",
+	"Cannot create record":                 "Жазбаны құру мүмкін емес",
+	"Cannot update record":                 "Жазбаны жаңарту мүмкін емес",
+	"Cannot delete record":                 "Жазбаны жою мүмкін емес",
+	"Cannot view record":                   "Жазбаны алу мүмкін емес",
+	"value param not found":                "value параметрі табылмады",
+	"Parse Input Error":                    "Сұранысты талдау қатесі",
+	"allowed keys {keys}":                  "Рұқсат етілген кілттер {keys}",
+	"interval {interval} not allowed":      "{interval} аралығына рұқсат жоқ",
+	"metrics not found":                    "Метрикалар табылмады",
+	"{field} - field is read only":         "{field} - өрісті өзгертуге рұқсат жоқ",
+	"Cannot save view":                     "Көріністі сақтау мүмкін емес",
+	"saved view not found":                 "Сақталған көрініс табылмады",
+	"role {role} not allowed":              "{role} рөліне рұқсат жоқ",
+	"record not found":                     "Жазба табылмады",
+	"internal error":                       "Сервердің ішкі қатесі",
+	"db.error":                             "Дерекқор қатесі",
+	"db.unique_violation":                  "Мұндай жазба бұрыннан бар",
+	"db.foreign_key_violation":             "Басқа жазбамен байланыс бұзылды",
+	"db.not_null_violation":                "Міндетті мән толтырылмаған",
+	"db.check_violation":                   "Мән тексеруден өтпеді",
+	"db.invalid_input":                     "Қате мән",
+	"record would leave the allowed scope": "Жазба рұқсат етілген аймақтан шығады",
+	"user is not authorized":               "Пайдаланушы авторизацияланбаған",
+}
+
+var enMessages = map[string]string{
+	"validation.required":                  "{field} - cannot be blank",
+	"validation.in":                        "{field} - must be one of {values}",
+	"validation.email":                     "{field} invalid Email address",
+	"validation.url":                       "{field} invalid URL address",
+	"validation.length":                    "{field} length must be between {min} - {max}",
+	"validation.match":                     "{field} has invalid format",
+	"validation.number":                    "{field} must be a number",
+	"validation.range":                     "{field} must be between {min} - {max}",
+	"validation.min":                       "{field} must be no less than {min}",
+	"validation.max":                       "{field} must be no greater than {max}",
+	"validation.date":                      "{field} invalid date",
+	"validation.date_range":                "{field} date is out of the allowed range",
+	"validation.phone":                     "{field} invalid phone number",
+	"validation.uuid":                      "{field} invalid UUID",
+	"validation.ip":                        "{field} invalid IP address",
+	"validation.unique":                    "{field} - value already exists",
+	"validation.exists":                    "{field} - record not found",
+	"validation.foreign_key":               "{field} - related record not found",
+	"validation.compare.eq":                "{field} must be equal to {other_field}",
+	"validation.compare.ne":                "{field} must not be equal to {other_field}",
+	"validation.compare.gt":                "{field} must be greater than {other_field}",
+	"validation.compare.gte":               "{field} must be no less than {other_field}",
+	"validation.compare.lt":                "{field} must be less than {other_field}",
+	"validation.compare.lte":               "{field} must be no greater than {other_field}",
+	"validation.type":                      "{field} must be of type {type}",
+	"validation.unknown_field":             "{field} - unknown field",
+	"validation.convert":                   "{field} - invalid value",
+	"Cannot create record":                 "Cannot create record",
+	"Cannot update record":                 "Cannot update record",
+	"Cannot delete record":                 "Cannot delete record",
+	"Cannot view record":                   "Cannot view record",
+	"value param not found":                "value param not found",
+	"Parse Input Error":                    "Parse Input Error",
+	"allowed keys {keys}":                  "allowed keys {keys}",
+	"interval {interval} not allowed":      "interval {interval} not allowed",
+	"metrics not found":                    "metrics not found",
+	"{field} - field is read only":         "{field} - field is read only",
+	"Cannot save view":                     "Cannot save view",
+	"saved view not found":                 "saved view not found",
+	"role {role} not allowed":              "role {role} not allowed",
+	"record not found":                     "record not found",
+	"internal error":                       "internal error",
+	"db.error":                             "database error",
+	"db.unique_violation":                  "record already exists",
+	"db.foreign_key_violation":             "related record constraint violated",
+	"db.not_null_violation":                "required value is missing",
+	"db.check_violation":                   "value failed the check",
+	"db.invalid_input":                     "invalid value",
+	"record would leave the allowed scope": "record would leave the allowed scope",
+	"user is not authorized":               "user is not authorized",
+}
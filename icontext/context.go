@@ -4,6 +4,7 @@ import (
 	"context"
 
 	"github.com/portalenergy/pe-api-admin/app/models"
+	"github.com/portalenergy/pe-request-generator/i18n"
 	"github.com/rs/xid"
 	log "github.com/sirupsen/logrus"
 )
@@ -14,6 +15,7 @@ const (
 	UserContext         = key("userContext")
 	LoggerContextKey    = key("loggerContextKey")
 	RequestIDContextKey = key("requestIDContextKey")
+	LocaleContextKey    = key("localeContextKey")
 )
 
 func GetContext() context.Context {
@@ -37,6 +39,12 @@ func GetUser(ctx context.Context) (*models.User, bool) {
 	return u, ok
 }
 
+// GetLocale - return locale chosen for the request if it exists.
+func GetLocale(ctx context.Context) (i18n.Locale, bool) {
+	u, ok := ctx.Value(LocaleContextKey).(i18n.Locale)
+	return u, ok
+}
+
 // GetLogger - return logger instance from context if it exists.
 func GetLogger(ctx context.Context) (*log.Entry, bool) {
 	u, ok := ctx.Value(LoggerContextKey).(*log.Entry)
@@ -0,0 +1,48 @@
+// Package identifier validates and quotes sql identifiers. It is shared by the
+// query builder and the database rules so that every table and column pasted
+// into sql passes the same check.
+package identifier
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// ErrInvalid is returned when a table, column, alias or select
+// function can not be used in a query.
+var ErrInvalid = errors.New("invalid identifier")
+
+var identifierRegexp = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]{0,62}$`)
+
+// IsValid reports whether name is a plain sql identifier.
+func IsValid(name string) bool {
+	return identifierRegexp.MatchString(name)
+}
+
+// Invalid returns ErrInvalid for the name of the given kind.
+func Invalid(kind string, name string) error {
+	return fmt.Errorf("%w: %s %q", ErrInvalid, kind, name)
+}
+
+// Validate returns ErrInvalid for the first name which is not a plain identifier.
+func Validate(kind string, names ...string) error {
+	for _, name := range names {
+		if !IsValid(name) {
+			return Invalid(kind, name)
+		}
+	}
+	return nil
+}
+
+// Quote is the single place identifiers are quoted,
+// names must have passed IsValid before.
+func Quote(name string) string {
+	return fmt.Sprintf(`"%s"`, strings.ReplaceAll(name, `"`, `""`))
+}
+
+// QuoteTable quotes a table of the public schema.
+func QuoteTable(name string) string {
+	return fmt.Sprintf(`public.%s`, Quote(name))
+}
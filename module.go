@@ -15,6 +15,18 @@ type BaseModule struct {
 	Fields     []fields.ModuleField       `json:"fields"`
 	Defrec     actions.DefrecModuleAction `json:"defrec"`
 	Actions    []actions.ModuleAction     `json:"actions"`
+	Timestamps ModuleTimestamps           `json:"-"`
+	// Policy returns the row scope of the request user, it is ANDed with the
+	// action where of list, view, update, delete and aggregate. nil allows every row.
+	Policy func(c *gin.Context) *actions.ModuleActionWhere `json:"-"`
+}
+
+// Scope combines the action where with the module policy of the request.
+func (module BaseModule) Scope(c *gin.Context, where *actions.ModuleActionWhere) *actions.ModuleActionWhere {
+	if module.Policy == nil {
+		return where
+	}
+	return actions.AndWhere(where, module.Policy(c))
 }
 
 func (module BaseModule) GetField(fieldName string) *fields.ModuleField {
@@ -48,3 +60,19 @@ func (module BaseModule) GetRules(context *gin.Context, field fields.ModuleField
 	}
 	return checkRules
 }
+
+// hasConditions reports whether a field of the module is conditional
+// or has cross field rules on update.
+func (module BaseModule) hasConditions(c *gin.Context) bool {
+	for _, field := range module.Fields {
+		if field.VisibleIf != nil {
+			return true
+		}
+		for _, rule := range module.GetRules(c, field, fields.ScenarioUpdate) {
+			if _, ok := rule.(fields.CrossFieldRules); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,142 @@
+package response
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"regexp"
+
+	"github.com/lib/pq"
+	"github.com/portalenergy/pe-request-generator/errs"
+	"github.com/portalenergy/pe-request-generator/i18n"
+)
+
+const (
+	ErrorCodeBadRequest          string = "bad_request"
+	ErrorCodeUnauthorized        string = "unauthorized"
+	ErrorCodeForbidden           string = "forbidden"
+	ErrorCodeNotFound            string = "not_found"
+	ErrorCodeValidation          string = "validation"
+	ErrorCodeInternal            string = "internal"
+	ErrorCodeDB                  string = "db.error"
+	ErrorCodeUniqueViolation     string = "db.unique_violation"
+	ErrorCodeForeignKeyViolation string = "db.foreign_key_violation"
+	ErrorCodeNotNullViolation    string = "db.not_null_violation"
+	ErrorCodeCheckViolation      string = "db.check_violation"
+	ErrorCodeInvalidInput        string = "db.invalid_input"
+)
+
+// pqErrorCodes maps postgres error codes to the error model codes.
+var pqErrorCodes = map[pq.ErrorCode]string{
+	"23505": ErrorCodeUniqueViolation,
+	"23503": ErrorCodeForeignKeyViolation,
+	"23502": ErrorCodeNotNullViolation,
+	"23514": ErrorCodeCheckViolation,
+	"22P02": ErrorCodeInvalidInput,
+	"22001": ErrorCodeInvalidInput,
+	"22003": ErrorCodeInvalidInput,
+	"22007": ErrorCodeInvalidInput,
+	"22008": ErrorCodeInvalidInput,
+}
+
+var errorCodeStatuses = map[string]int{
+	ErrorCodeBadRequest:          http.StatusBadRequest,
+	ErrorCodeUnauthorized:        http.StatusUnauthorized,
+	ErrorCodeForbidden:           http.StatusForbidden,
+	ErrorCodeNotFound:            http.StatusNotFound,
+	ErrorCodeValidation:          http.StatusBadRequest,
+	ErrorCodeInternal:            http.StatusInternalServerError,
+	ErrorCodeDB:                  http.StatusInternalServerError,
+	ErrorCodeUniqueViolation:     http.StatusConflict,
+	ErrorCodeForeignKeyViolation: http.StatusConflict,
+	ErrorCodeNotNullViolation:    http.StatusBadRequest,
+	ErrorCodeCheckViolation:      http.StatusBadRequest,
+	ErrorCodeInvalidInput:        http.StatusBadRequest,
+}
+
+// pqDetailKeyRegexp reads the column list from details like `Key (email)=(a@b.c) already exists.`
+var pqDetailKeyRegexp = regexp.MustCompile(`^Key \(([^)]+)\)=`)
+
+// Error is a single machine readable failure, Field is the path of the
+// request value it belongs to and Params are the parameters of the failed rule.
+type Error struct {
+	Code    string                 `json:"code"`
+	Field   string                 `json:"field,omitempty"`
+	Message string                 `json:"message"`
+	Params  map[string]interface{} `json:"params,omitempty"`
+}
+
+func NewError(code string, field string, message string) Error {
+	return Error{
+		Code:    code,
+		Field:   field,
+		Message: message,
+	}
+}
+
+func (err Error) Error() string {
+	return err.Message
+}
+
+// Status returns the http status of the error code,
+// unknown codes such as the validation rule codes are bad requests.
+func (err Error) Status() int {
+	if status, ok := errorCodeStatuses[err.Code]; ok {
+		return status
+	}
+	return http.StatusBadRequest
+}
+
+// BadRequestOf converts the error of a request hook to a bad request with its
+// message, typed errors keep their code.
+func BadRequestOf(err error) Error {
+	var typedError Error
+	if errors.As(err, &typedError) {
+		return typedError
+	}
+	return NewError(ErrorCodeBadRequest, "", err.Error())
+}
+
+// ErrorOf converts any error to the error model with messages in the default locale.
+func ErrorOf(err error) Error {
+	return errorOf(i18n.DefaultLocale, err)
+}
+
+// errorOf converts any error to the error model, postgres errors are mapped by their
+// code, errs.ErrNotFound/sql.ErrNoRows become not_found and errs.ErrOutOfScope forbidden. Messages of postgres and
+// unknown errors are generic, their details stay in the log and never reach the client.
+func errorOf(locale i18n.Locale, err error) Error {
+	var typedError Error
+	if errors.As(err, &typedError) {
+		return typedError
+	}
+
+	if errors.Is(err, errs.ErrNotFound) || errors.Is(err, sql.ErrNoRows) {
+		return NewError(ErrorCodeNotFound, "", i18n.Translate(locale, "record not found", nil))
+	}
+
+	if errors.Is(err, errs.ErrOutOfScope) {
+		return NewError(ErrorCodeForbidden, "", i18n.Translate(locale, "record would leave the allowed scope", nil))
+	}
+
+	var pqError *pq.Error
+	if errors.As(err, &pqError) {
+		code, ok := pqErrorCodes[pqError.Code]
+		if !ok {
+			code = ErrorCodeDB
+		}
+
+		result := NewError(code, pqError.Column, i18n.Translate(locale, code, nil))
+		if matches := pqDetailKeyRegexp.FindStringSubmatch(pqError.Detail); len(matches) > 1 && len(result.Field) == 0 {
+			result.Field = matches[1]
+		}
+		if len(pqError.Constraint) > 0 {
+			result.Params = map[string]interface{}{
+				"constraint": pqError.Constraint,
+			}
+		}
+		return result
+	}
+
+	return NewError(ErrorCodeInternal, "", i18n.Translate(locale, "internal error", nil))
+}
@@ -3,12 +3,15 @@ package response
 import (
 	"encoding/json"
 	"github.com/gin-gonic/gin"
+	"github.com/portalenergy/pe-request-generator/i18n"
+	"github.com/portalenergy/pe-request-generator/icontext"
 	log "github.com/sirupsen/logrus"
 	"net/http"
 )
 
 type responseBase struct {
 	StatusCode int         `json:"statusCode"`
+	Code       string      `json:"code,omitempty"`
 	Message    string      `json:"message"`
 	Errors     interface{} `json:"errors"`
 }
@@ -52,3 +55,41 @@ func ErrorResponse(l *log.Entry, c *gin.Context, code int, message string, data
 		}).Error("Error json encode in Response")
 	}
 }
+
+// TypedErrorResponse generate a error response for err with the status and code of the error model,
+// server errors are logged and answered with the generic message only.
+func TypedErrorResponse(l *log.Entry, c *gin.Context, message string, err error) {
+	typedError := errorOf(RequestLocale(c), err)
+	if typedError.Status() >= http.StatusInternalServerError {
+		l.Errorln("INTERNAL ERR: ", err)
+		message = typedError.Message
+	}
+	ErrorsResponse(l, c, typedError.Status(), typedError.Code, message, []Error{typedError})
+}
+
+// RequestLocale returns the locale stored in the request context,
+// or the best match of the Accept-Language header.
+func RequestLocale(c *gin.Context) i18n.Locale {
+	if locale, ok := icontext.GetLocale(c.Request.Context()); ok {
+		return locale
+	}
+	return i18n.ParseAcceptLanguage(c.GetHeader("Accept-Language"))
+}
+
+// ErrorsResponse generate a error response with typed errors
+func ErrorsResponse(l *log.Entry, c *gin.Context, statusCode int, code string, message string, errs []Error) {
+	c.Writer.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	c.Writer.WriteHeader(statusCode)
+	response := &responseBase{
+		StatusCode: statusCode,
+		Code:       code,
+		Message:    message,
+		Errors:     errs,
+	}
+
+	if err := json.NewEncoder(c.Writer).Encode(response); err != nil {
+		l.WithFields(log.Fields{
+			"message": message,
+		}).Error("Error json encode in Response")
+	}
+}
@@ -0,0 +1,404 @@
+package module
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/portalenergy/pe-request-generator/actions"
+	"github.com/portalenergy/pe-request-generator/db"
+	"github.com/portalenergy/pe-request-generator/fields"
+	"github.com/portalenergy/pe-request-generator/icontext"
+	"github.com/portalenergy/pe-request-generator/response"
+	"github.com/portalenergy/pe-request-generator/utils"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	GeneratorErrorSavedView string = "Cannot save view"
+
+	defaultSavedViewsTable = "saved_views"
+	savedViewNameMaxLength = 255
+)
+
+// SavedViews stores the list views of the users: the filters, search, sort and
+// visible columns of a module list. The table has the columns id, module, user_id,
+// role, name, filter, search, sort, fields, created_ts and updated_ts, filter holds
+// the filter params as a json object and sort and fields are comma separated.
+type SavedViews struct {
+	// TableName defaults to saved_views.
+	TableName string
+	// User overrides the owner of the views, by default it is the id of the context user.
+	// Views are owned by the user who created them.
+	User func(c *gin.Context) interface{}
+	// Roles returns the roles of the request user. A view shared with a role is
+	// visible to every user with it, only the owner may change or delete it.
+	Roles func(c *gin.Context) []string
+}
+
+// SavedView is one saved list view, role is empty for private views.
+type SavedView struct {
+	ID     interface{}       `json:"id"`
+	Name   string            `json:"name"`
+	Role   string            `json:"role"`
+	Filter map[string]string `json:"filter"`
+	Search string            `json:"search"`
+	Sort   []string          `json:"sort"`
+	Fields []string          `json:"fields"`
+	Owner  bool              `json:"owner"`
+}
+
+var savedViewFields = []fields.ModuleField{
+	{Name: "id"},
+	{Name: "user_id"},
+	{Name: "role"},
+	{Name: "name"},
+	{Name: "filter"},
+	{Name: "search"},
+	{Name: "sort"},
+	{Name: "fields"},
+}
+
+// listParams are the list query params a saved view presets.
+type listParams struct {
+	Filter map[string]string
+	Search string
+	Sort   string
+	Fields string
+}
+
+func requestListParams(c *gin.Context) listParams {
+	return listParams{
+		Filter: c.QueryMap("filter"),
+		Search: c.Query("search"),
+		Sort:   c.Query("sort"),
+		Fields: c.Query("fields"),
+	}
+}
+
+// withView fills the params missing from the request with the view,
+// filters are merged by name.
+func (params listParams) withView(view SavedView) listParams {
+	filter := make(map[string]string)
+	for name, value := range view.Filter {
+		filter[name] = value
+	}
+	for name, value := range params.Filter {
+		filter[name] = value
+	}
+	params.Filter = filter
+
+	if len(params.Search) == 0 {
+		params.Search = view.Search
+	}
+	if len(params.Sort) == 0 {
+		params.Sort = strings.Join(view.Sort, ",")
+	}
+	if len(params.Fields) == 0 {
+		params.Fields = strings.Join(view.Fields, ",")
+	}
+	return params
+}
+
+func (views SavedViews) tableName() string {
+	if len(views.TableName) == 0 {
+		return defaultSavedViewsTable
+	}
+	return views.TableName
+}
+
+// user returns the owner of the request views, nil when the request has no user.
+func (views SavedViews) user(c *gin.Context) interface{} {
+	if views.User != nil {
+		return views.User(c)
+	}
+	return contextUser(c)
+}
+
+// requestUser returns the owner of the request views, ok is false when the
+// request has no user and the error response is written.
+func (views SavedViews) requestUser(c *gin.Context, l *log.Entry) (interface{}, bool) {
+	user := views.user(c)
+	if user == nil {
+		response.TypedErrorResponse(l, c, translate(c, "user is not authorized", nil), response.NewError(
+			response.ErrorCodeUnauthorized,
+			"",
+			translate(c, "user is not authorized", nil),
+		))
+		return nil, false
+	}
+	return user, true
+}
+
+func (views SavedViews) roles(c *gin.Context) []string {
+	if views.Roles == nil {
+		return nil
+	}
+	return views.Roles(c)
+}
+
+// module describes the views table for the executor of the listed module.
+func (views SavedViews) module(module *BaseModule) *BaseModule {
+	return &BaseModule{
+		Name:       module.Name,
+		Path:       module.Path,
+		TableName:  views.tableName(),
+		PrimaryKey: "id",
+		Fields:     savedViewFields,
+		Timestamps: ModuleTimestamps{OnUpdate: TimestampColumnsUpdated},
+	}
+}
+
+// visibleWhere matches the views of the module owned by the request user
+// or shared with one of the user roles.
+func (views SavedViews) visibleWhere(c *gin.Context, module *BaseModule) *actions.ModuleActionWhere {
+	visible := actions.Eq("user_id", views.user(c))
+	if roles := views.roles(c); len(roles) > 0 {
+		values := make([]interface{}, 0, len(roles))
+		for _, role := range roles {
+			values = append(values, role)
+		}
+		visible = actions.Or(visible, actions.In("role", values...))
+	}
+	return actions.WhereCondition(actions.Eq("module", module.Name), visible)
+}
+
+// ownWhere matches the views of the module owned by the request user.
+func (views SavedViews) ownWhere(c *gin.Context, module *BaseModule) *actions.ModuleActionWhere {
+	return actions.WhereCondition(actions.Eq("module", module.Name), actions.Eq("user_id", views.user(c)))
+}
+
+// savedViewOf reads a row of the views table.
+func (views SavedViews) savedViewOf(c *gin.Context, result interface{}) SavedView {
+	row, _ := result.(map[string]interface{})
+	view := SavedView{
+		ID:     row["id"],
+		Name:   stringValue(row["name"]),
+		Role:   stringValue(row["role"]),
+		Filter: make(map[string]string),
+		Search: stringValue(row["search"]),
+		Sort:   splitParam(stringValue(row["sort"])),
+		Fields: splitParam(stringValue(row["fields"])),
+		Owner:  fmt.Sprint(row["user_id"]) == fmt.Sprint(views.user(c)),
+	}
+	if filter := stringValue(row["filter"]); len(filter) > 0 {
+		if err := json.Unmarshal([]byte(filter), &view.Filter); err != nil {
+			view.Filter = make(map[string]string)
+		}
+	}
+	return view
+}
+
+// input maps the view to the columns of the views table, ok is false when the
+// request is invalid and the error response is written.
+func (views SavedViews) input(c *gin.Context, l *log.Entry) (map[string]interface{}, bool) {
+	var view SavedView
+	err := utils.ParseJson(c.Request, &view)
+	if err != nil {
+		response.TypedErrorResponse(l, c, translate(c, GeneratorErrorSavedView, nil), response.NewError(
+			response.ErrorCodeBadRequest,
+			"",
+			translate(c, "Parse Input Error", nil),
+		))
+		return nil, false
+	}
+
+	if len(view.Role) > 0 && !containsStrings(views.roles(c), view.Role) {
+		response.ErrorsResponse(l, c, http.StatusForbidden, response.ErrorCodeForbidden, translate(c, GeneratorErrorSavedView, nil), []response.Error{
+			response.NewError(
+				response.ErrorCodeForbidden,
+				"role",
+				translate(c, "role {role} not allowed", map[string]interface{}{"role": view.Role}),
+			),
+		})
+		return nil, false
+	}
+
+	view.Name = strings.TrimSpace(view.Name)
+	if len(view.Name) == 0 || len(view.Name) > savedViewNameMaxLength {
+		response.ErrorsResponse(l, c, http.StatusBadRequest, response.ErrorCodeValidation, translate(c, GeneratorErrorSavedView, nil), []response.Error{
+			typedError(c, "name", fields.NewRuleError(fields.RuleCodeLength, "name", map[string]interface{}{
+				"min": 1,
+				"max": savedViewNameMaxLength,
+			})),
+		})
+		return nil, false
+	}
+
+	filter := view.Filter
+	if filter == nil {
+		filter = make(map[string]string)
+	}
+	filterJson, err := json.Marshal(filter)
+	if err != nil {
+		response.TypedErrorResponse(l, c, translate(c, GeneratorErrorSavedView, nil), err)
+		return nil, false
+	}
+
+	var role interface{}
+	if len(view.Role) > 0 {
+		role = view.Role
+	}
+
+	return map[string]interface{}{
+		"name":   view.Name,
+		"role":   role,
+		"filter": string(filterJson),
+		"search": view.Search,
+		"sort":   strings.Join(splitParam(strings.Join(view.Sort, ",")), ","),
+		"fields": strings.Join(splitParam(strings.Join(view.Fields, ",")), ","),
+	}, true
+}
+
+func stringValue(value interface{}) string {
+	if value == nil {
+		return ""
+	}
+	return fmt.Sprint(value)
+}
+
+// savedView loads a view of the module visible to the request user.
+func (generator *Generator) savedView(c *gin.Context, l *log.Entry, module *BaseModule, id string) (SavedView, error) {
+	if generator.SavedViews == nil {
+		return SavedView{}, db.ErrNotFound
+	}
+	views := *generator.SavedViews
+	viewsModule := views.module(module)
+
+	result, err := generator.db(viewsModule).View(l, viewsModule.TableName, viewsModule.PrimaryKey, viewsModule.Fields, []interface{}{viewsModule.PrimaryKey}, []interface{}{id}, views.visibleWhere(c, module), nil)
+	if err != nil {
+		return SavedView{}, err
+	}
+	return views.savedViewOf(c, result), nil
+}
+
+func (generator *Generator) actionSavedViewList(module *BaseModule) func(c *gin.Context) {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+		l, _ := icontext.GetLogger(ctx)
+
+		views := *generator.SavedViews
+		viewsModule := views.module(module)
+		if _, ok := views.requestUser(c, l); !ok {
+			return
+		}
+
+		results, _, err := generator.db(viewsModule).List(
+			l,
+			viewsModule.TableName,
+			viewsModule.PrimaryKey,
+			viewsModule.Fields,
+			0,
+			3000,
+			nil,
+			"",
+			nil,
+			nil,
+			views.visibleWhere(c, module),
+			nil,
+			[]actions.ModuleActionSort{{Name: "name"}},
+			nil,
+		)
+		if err != nil {
+			response.TypedErrorResponse(l, c, err.Error(), err)
+			return
+		}
+
+		rows := make([]SavedView, 0, len(results))
+		for _, result := range results {
+			rows = append(rows, views.savedViewOf(c, result))
+		}
+
+		output := struct {
+			Rows []SavedView `json:"rows"`
+		}{
+			Rows: rows,
+		}
+		response.Response(l, c, output)
+	}
+}
+
+func (generator *Generator) actionSavedViewAdd(module *BaseModule) func(c *gin.Context) {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+		l, _ := icontext.GetLogger(ctx)
+
+		views := *generator.SavedViews
+		viewsModule := views.module(module)
+		user, ok := views.requestUser(c, l)
+		if !ok {
+			return
+		}
+
+		input, ok := views.input(c, l)
+		if !ok {
+			return
+		}
+		input["module"] = module.Name
+		input["user_id"] = user
+		viewsModule.Timestamps.ApplyCreate(c, input)
+
+		result, _, err := generator.db(viewsModule).Add(l, viewsModule.TableName, viewsModule.PrimaryKey, viewsModule.Fields, input)
+		if err != nil {
+			response.TypedErrorResponse(l, c, translate(c, GeneratorErrorSavedView, nil), err)
+			return
+		}
+
+		response.Response(l, c, views.savedViewOf(c, result))
+	}
+}
+
+func (generator *Generator) actionSavedViewUpdate(module *BaseModule) func(c *gin.Context) {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+		l, _ := icontext.GetLogger(ctx)
+
+		views := *generator.SavedViews
+		viewsModule := views.module(module)
+		if _, ok := views.requestUser(c, l); !ok {
+			return
+		}
+
+		input, ok := views.input(c, l)
+		if !ok {
+			return
+		}
+		viewsModule.Timestamps.ApplyUpdate(c, input)
+
+		result, err := generator.db(viewsModule).Update(l, viewsModule.TableName, viewsModule.PrimaryKey, viewsModule.Fields, input, viewsModule.PrimaryKey, c.Param("id"), views.ownWhere(c, module))
+		if err != nil {
+			response.TypedErrorResponse(l, c, translate(c, GeneratorErrorSavedView, nil), err)
+			return
+		}
+
+		response.Response(l, c, views.savedViewOf(c, result))
+	}
+}
+
+func (generator *Generator) actionSavedViewDelete(module *BaseModule) func(c *gin.Context) {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+		l, _ := icontext.GetLogger(ctx)
+
+		views := *generator.SavedViews
+		viewsModule := views.module(module)
+		if _, ok := views.requestUser(c, l); !ok {
+			return
+		}
+
+		err := generator.db(viewsModule).Delete(l, viewsModule.TableName, viewsModule.PrimaryKey, c.Param("id"), views.ownWhere(c, module))
+		if err != nil {
+			response.TypedErrorResponse(l, c, translate(c, GeneratorErrorDelete, nil), err)
+			return
+		}
+
+		output := struct {
+			Delete bool `json:"delete"`
+		}{
+			Delete: true,
+		}
+		response.Response(l, c, output)
+	}
+}
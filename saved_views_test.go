@@ -0,0 +1,172 @@
+package module
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/portalenergy/pe-request-generator/db"
+	"github.com/sirupsen/logrus"
+)
+
+func TestListParamsWithView(t *testing.T) {
+	view := SavedView{
+		Filter: map[string]string{"status": "active", "city": "Almaty"},
+		Search: "north",
+		Sort:   []string{"-created_ts", "name"},
+		Fields: []string{"id", "name"},
+	}
+
+	params := listParams{Filter: map[string]string{"city": "Astana"}, Sort: "name"}.withView(view)
+	expected := listParams{
+		Filter: map[string]string{"status": "active", "city": "Astana"},
+		Search: "north",
+		Sort:   "name",
+		Fields: "id,name",
+	}
+	if !reflect.DeepEqual(params, expected) {
+		t.Fatalf("expected the request params to win over the view, got %#v", params)
+	}
+	if view.Filter["city"] != "Almaty" {
+		t.Fatalf("the view filter must not change, got %v", view.Filter)
+	}
+
+	params = listParams{Search: "south", Fields: "id"}.withView(SavedView{})
+	if params.Search != "south" || params.Fields != "id" || len(params.Sort) > 0 || len(params.Filter) > 0 {
+		t.Fatalf("expected the request params without a view preset, got %#v", params)
+	}
+}
+
+func TestSavedViewsWhere(t *testing.T) {
+	module := &BaseModule{Name: "stations"}
+	render := func(views SavedViews, shared bool) (string, []interface{}) {
+		where := views.ownWhere(userContext(7), module)
+		if shared {
+			where = views.visibleWhere(userContext(7), module)
+		}
+		pq := db.PostgresQuery{TableName: "saved_views", PrimaryKey: "id", Fields: []string{"name"}, Where: where, Size: 1}
+		query, values := pq.GetQuery(false)
+		return query[strings.Index(query, "WHERE"):strings.Index(query, " GROUP BY")], values
+	}
+
+	tests := []struct {
+		name   string
+		views  SavedViews
+		shared bool
+		where  string
+		values []interface{}
+	}{
+		{
+			"visible to the owner or a role of the user",
+			SavedViews{Roles: func(c *gin.Context) []string { return []string{"ops", "billing"} }},
+			true,
+			`WHERE (parent."module" = $1 AND (parent."user_id" = $2 OR parent."role" IN ($3,$4)))`,
+			[]interface{}{"stations", int64(7), "ops", "billing"},
+		},
+		{
+			"visible to the owner without roles",
+			SavedViews{},
+			true,
+			`WHERE (parent."module" = $1 AND parent."user_id" = $2)`,
+			[]interface{}{"stations", int64(7)},
+		},
+		{
+			"changed by the owner only",
+			SavedViews{Roles: func(c *gin.Context) []string { return []string{"ops"} }},
+			false,
+			`WHERE (parent."module" = $1 AND parent."user_id" = $2)`,
+			[]interface{}{"stations", int64(7)},
+		},
+		{
+			"owner override",
+			SavedViews{User: func(c *gin.Context) interface{} { return "u-1" }},
+			false,
+			`WHERE (parent."module" = $1 AND parent."user_id" = $2)`,
+			[]interface{}{"stations", "u-1"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			where, values := render(test.views, test.shared)
+			if where != test.where {
+				t.Fatalf("unexpected where:\n%s\n%s", where, test.where)
+			}
+			if !reflect.DeepEqual(values, test.values) {
+				t.Fatalf("expected values %v, got %v", test.values, values)
+			}
+		})
+	}
+}
+
+func TestSavedViewInput(t *testing.T) {
+	views := SavedViews{Roles: func(c *gin.Context) []string { return []string{"ops"} }}
+
+	tests := []struct {
+		name   string
+		body   string
+		status int
+		input  map[string]interface{}
+	}{
+		{
+			"private",
+			`{"name": " Active ", "filter": {"status": "active"}, "sort": ["-created_ts", " ", "name"], "fields": ["id"]}`,
+			http.StatusOK,
+			map[string]interface{}{"name": "Active", "role": nil, "filter": `{"status":"active"}`, "search": "", "sort": "-created_ts,name", "fields": "id"},
+		},
+		{
+			"shared with a role of the user",
+			`{"name": "Ops", "role": "ops"}`,
+			http.StatusOK,
+			map[string]interface{}{"name": "Ops", "role": "ops", "filter": `{}`, "search": "", "sort": "", "fields": ""},
+		},
+		{"shared with another role", `{"name": "Billing", "role": "billing"}`, http.StatusForbidden, nil},
+		{"without name", `{"name": "  "}`, http.StatusBadRequest, nil},
+		{"malformed", `{"name": `, http.StatusBadRequest, nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(recorder)
+			c.Request = httptest.NewRequest("PUT", "/", strings.NewReader(test.body))
+
+			input, ok := views.input(c, logrus.NewEntry(logrus.New()))
+			if ok != (test.status == http.StatusOK) {
+				t.Fatalf("expected ok %v, got %v", test.status == http.StatusOK, ok)
+			}
+			if !ok {
+				if recorder.Code != test.status {
+					t.Fatalf("expected status %d, got %d %s", test.status, recorder.Code, recorder.Body.String())
+				}
+				return
+			}
+			if !reflect.DeepEqual(input, test.input) {
+				t.Fatalf("expected input %v, got %v", test.input, input)
+			}
+		})
+	}
+}
+
+func TestSavedViewsRequireUser(t *testing.T) {
+	generator := &Generator{SavedViews: &SavedViews{}}
+	recorder := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(recorder)
+	c.Request = httptest.NewRequest("DELETE", "/", nil)
+
+	generator.actionSavedViewDelete(&BaseModule{Name: "stations"})(c)
+	if recorder.Code != http.StatusUnauthorized {
+		t.Fatalf("expected the views of a request without user to be refused, got %d", recorder.Code)
+	}
+
+	view := SavedViews{}.savedViewOf(userContext(7), map[string]interface{}{"id": 1, "user_id": int64(7), "sort": "-created_ts,name", "filter": `{"status":"active"}`})
+	if !view.Owner || len(view.Sort) != 2 || view.Filter["status"] != "active" {
+		t.Fatalf("expected the view of the context user, got %#v", view)
+	}
+	if view := (SavedViews{}).savedViewOf(userContext(8), map[string]interface{}{"user_id": int64(7)}); view.Owner {
+		t.Fatalf("expected a shared view not to be owned, got %#v", view)
+	}
+}
@@ -0,0 +1,131 @@
+package module
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultCreatedColumn = "created_ts"
+	defaultUpdatedColumn = "updated_ts"
+)
+
+type TimestampFormat string
+
+const (
+	TimestampFormatUnix        TimestampFormat = "unix"
+	TimestampFormatUnixMilli   TimestampFormat = "unix_milli"
+	TimestampFormatTimestamptz TimestampFormat = "timestamptz"
+)
+
+// TimestampColumns selects which timestamp columns are written by an action.
+type TimestampColumns string
+
+const (
+	TimestampColumnsBoth    TimestampColumns = "both"
+	TimestampColumnsCreated TimestampColumns = "created"
+	TimestampColumnsUpdated TimestampColumns = "updated"
+	TimestampColumnsNone    TimestampColumns = "none"
+)
+
+// ModuleTimestamps describes the bookkeeping columns written by add and update.
+// The zero value keeps the historical behaviour: created_ts and updated_ts are
+// set to unix seconds on add and nothing is touched on update.
+type ModuleTimestamps struct {
+	Disabled        bool
+	Format          TimestampFormat
+	CreatedColumn   string
+	UpdatedColumn   string
+	OnCreate        TimestampColumns
+	OnUpdate        TimestampColumns
+	CreatedByColumn string
+	UpdatedByColumn string
+	// User overrides the value of the created/updated by columns,
+	// by default it is the id of the context user.
+	User func(c *gin.Context) interface{}
+}
+
+// user returns the value of the created/updated by columns, nil when the request has no user.
+func (timestamps ModuleTimestamps) user(c *gin.Context) interface{} {
+	if timestamps.User != nil {
+		return timestamps.User(c)
+	}
+	return contextUser(c)
+}
+
+func (timestamps ModuleTimestamps) createdColumn() string {
+	if len(timestamps.CreatedColumn) == 0 {
+		return defaultCreatedColumn
+	}
+	return timestamps.CreatedColumn
+}
+
+func (timestamps ModuleTimestamps) updatedColumn() string {
+	if len(timestamps.UpdatedColumn) == 0 {
+		return defaultUpdatedColumn
+	}
+	return timestamps.UpdatedColumn
+}
+
+func (timestamps ModuleTimestamps) value(now time.Time) interface{} {
+	switch timestamps.Format {
+	case TimestampFormatUnixMilli:
+		return now.UnixNano() / int64(time.Millisecond)
+	case TimestampFormatTimestamptz:
+		return now
+	}
+	return now.Unix()
+}
+
+// ApplyCreate adds the configured created/updated columns to an insert input.
+func (timestamps ModuleTimestamps) ApplyCreate(c *gin.Context, input map[string]interface{}) {
+	if timestamps.Disabled {
+		return
+	}
+
+	onCreate := timestamps.OnCreate
+	if len(onCreate) == 0 {
+		onCreate = TimestampColumnsBoth
+	}
+
+	now := timestamps.value(time.Now())
+	if onCreate == TimestampColumnsBoth || onCreate == TimestampColumnsCreated {
+		input[timestamps.createdColumn()] = now
+	}
+	if onCreate == TimestampColumnsBoth || onCreate == TimestampColumnsUpdated {
+		input[timestamps.updatedColumn()] = now
+	}
+
+	if len(timestamps.CreatedByColumn) == 0 && len(timestamps.UpdatedByColumn) == 0 {
+		return
+	}
+	user := timestamps.user(c)
+	if user == nil {
+		return
+	}
+	if len(timestamps.CreatedByColumn) > 0 {
+		input[timestamps.CreatedByColumn] = user
+	}
+	if len(timestamps.UpdatedByColumn) > 0 {
+		input[timestamps.UpdatedByColumn] = user
+	}
+}
+
+// ApplyUpdate adds the configured updated column to an update input,
+// only the updated column is ever written on update.
+func (timestamps ModuleTimestamps) ApplyUpdate(c *gin.Context, input map[string]interface{}) {
+	if timestamps.Disabled {
+		return
+	}
+
+	if timestamps.OnUpdate == TimestampColumnsBoth || timestamps.OnUpdate == TimestampColumnsUpdated {
+		input[timestamps.updatedColumn()] = timestamps.value(time.Now())
+	}
+
+	if len(timestamps.UpdatedByColumn) > 0 {
+		if user := timestamps.user(c); user != nil {
+			input[timestamps.UpdatedByColumn] = user
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package module
+
+import (
+	"context"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/portalenergy/pe-api-admin/app/models"
+	"github.com/portalenergy/pe-request-generator/icontext"
+)
+
+func userContext(id int64) *gin.Context {
+	c := testContext()
+	c.Request = c.Request.WithContext(context.WithValue(c.Request.Context(), icontext.UserContext, &models.User{ID: id}))
+	return c
+}
+
+func TestTimestampsUserDefaultsToContextUser(t *testing.T) {
+	timestamps := ModuleTimestamps{CreatedByColumn: "created_by", UpdatedByColumn: "updated_by", OnUpdate: TimestampColumnsUpdated}
+
+	input := make(map[string]interface{})
+	timestamps.ApplyCreate(userContext(7), input)
+	if input["created_by"] != int64(7) || input["updated_by"] != int64(7) {
+		t.Fatalf("expected the context user on create, got %v", input)
+	}
+
+	input = make(map[string]interface{})
+	timestamps.ApplyUpdate(testContext(), input)
+	if _, ok := input["updated_by"]; ok {
+		t.Fatalf("expected no updated by without a user, got %v", input)
+	}
+
+	timestamps.User = func(c *gin.Context) interface{} {
+		return "system"
+	}
+	input = make(map[string]interface{})
+	timestamps.ApplyUpdate(userContext(7), input)
+	if input["updated_by"] != "system" {
+		t.Fatalf("expected the user override on update, got %v", input)
+	}
+	if _, ok := input["created_by"]; ok {
+		t.Fatalf("created by must not change on update, got %v", input)
+	}
+}